@@ -0,0 +1,82 @@
+package pgdbtemplategoose
+
+import (
+	"database/sql"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	"github.com/andrei-polukhin/pgdbtemplate-goose/internal/sqldb"
+)
+
+// SQLDBConnection is implemented by connections
+// which can hand their underlying *sql.DB over to goose.
+//
+// Custom or decorated pgdbtemplate.DatabaseConnection implementations
+// (e.g. ones adding tracing) can implement it to work with the runner.
+type SQLDBConnection interface {
+	// SQLDB returns the *sql.DB backing the connection.
+	SQLDB() *sql.DB
+}
+
+// ConnectionExtractor extracts *sql.DB from a connection.
+//
+// It returns false if it does not support the given connection,
+// in which case the runner tries the next extractor.
+type ConnectionExtractor func(conn pgdbtemplate.DatabaseConnection) (*sql.DB, bool)
+
+// extractSQLDB extracts *sql.DB from the connection.
+//
+// Extractors registered with WithConnectionExtractor are tried first,
+// then SQLDBConnection, then pgdbtemplate-pq and pgdbtemplate-pgx connections.
+//
+// The returned release function must be called once the *sql.DB is no longer
+// needed. It closes databases opened by the runner itself and is a no-op
+// for databases owned by the connection. ErrRunnerClosed is returned
+// for pgdbtemplate-pgx connections once the runner has been closed.
+func (r *MigrationRunner) extractSQLDB(conn pgdbtemplate.DatabaseConnection) (*sql.DB, func() error, error) {
+	extractors := make([]sqldb.Extractor, len(r.extractors))
+	for i, extractor := range r.extractors {
+		extractors[i] = sqldb.Extractor(extractor)
+	}
+	db, opened, err := sqldb.Extract(conn, extractors...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !opened {
+		return db, noopRelease, nil
+	}
+
+	// The wrapper is owned by the runner and must be closed after the run.
+	release, err := r.trackDB(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, release, nil
+}
+
+// trackDB registers a *sql.DB opened by the runner, so that Close
+// can release it, and returns the function closing it after the run.
+func (r *MigrationRunner) trackDB(db *sql.DB) (func() error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		db.Close() // #nosec G104 -- Close error in error path is not critical.
+		return nil, ErrRunnerClosed
+	}
+	if r.openDBs == nil {
+		r.openDBs = make(map[*sql.DB]struct{})
+	}
+	r.openDBs[db] = struct{}{}
+
+	return func() error {
+		r.mu.Lock()
+		delete(r.openDBs, db)
+		r.mu.Unlock()
+		return db.Close()
+	}, nil
+}
+
+// noopRelease is the release function of databases owned by the connection.
+func noopRelease() error {
+	return nil
+}
@@ -0,0 +1,235 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepgx "github.com/andrei-polukhin/pgdbtemplate-pgx"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+// tracedConnection decorates a pgdbtemplate-pq connection
+// and exposes its *sql.DB via SQLDBConnection.
+type tracedConnection struct {
+	*pgdbtemplatepq.DatabaseConnection
+	queries int
+}
+
+func (c *tracedConnection) ExecContext(ctx context.Context, query string, args ...any) (any, error) {
+	c.queries++
+	return c.DatabaseConnection.ExecContext(ctx, query, args...)
+}
+
+func (c *tracedConnection) SQLDB() *sql.DB {
+	return c.DB
+}
+
+// opaqueConnection decorates a pgdbtemplate-pq connection
+// without exposing its *sql.DB.
+type opaqueConnection struct {
+	inner *pgdbtemplatepq.DatabaseConnection
+}
+
+func (c *opaqueConnection) ExecContext(ctx context.Context, query string, args ...any) (any, error) {
+	return c.inner.ExecContext(ctx, query, args...)
+}
+
+func (c *opaqueConnection) QueryRowContext(ctx context.Context, query string, args ...any) pgdbtemplate.Row {
+	return c.inner.QueryRowContext(ctx, query, args...)
+}
+
+func (c *opaqueConnection) Close() error {
+	return c.inner.Close()
+}
+
+func TestMigrationRunnerConnectionExtraction(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	migrations := map[string]string{
+		"00001_create_table.sql": `-- +goose Up
+CREATE TABLE goose_connection_test (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE goose_connection_test;
+`,
+	}
+
+	// connect opens a pgdbtemplate-pq connection to a fresh database.
+	connect := func(c *qt.C) *pgdbtemplatepq.DatabaseConnection {
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+			MigrationRunner:    &pgdbtemplate.NoOpMigrationRunner{},
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+
+		conn, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() {
+			conn.Close()
+			tm.DropTestDatabase(ctx, dbName)
+		})
+		return conn.(*pgdbtemplatepq.DatabaseConnection)
+	}
+
+	c.Run("SQLDBConnection implementation", func(c *qt.C) {
+		c.Parallel()
+
+		pqConn := connect(c)
+		runner := pgdbtemplategoose.NewMigrationRunner(writeMigrations(c, migrations))
+
+		err := runner.RunMigrations(ctx, &tracedConnection{DatabaseConnection: pqConn})
+		c.Assert(err, qt.IsNil)
+
+		var tableName string
+		err = pqConn.DB.QueryRowContext(ctx, `
+			SELECT table_name
+			FROM information_schema.tables
+			WHERE table_schema = 'public'
+			AND table_name = 'goose_connection_test'
+		`).Scan(&tableName)
+		c.Assert(err, qt.IsNil)
+	})
+
+	c.Run("Custom connection extractor", func(c *qt.C) {
+		c.Parallel()
+
+		pqConn := connect(c)
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithConnectionExtractor(func(conn pgdbtemplate.DatabaseConnection) (*sql.DB, bool) {
+				opaque, ok := conn.(*opaqueConnection)
+				if !ok {
+					return nil, false
+				}
+				return opaque.inner.DB, true
+			}),
+		)
+
+		err := runner.RunMigrations(ctx, &opaqueConnection{inner: pqConn})
+		c.Assert(err, qt.IsNil)
+
+		var tableName string
+		err = pqConn.DB.QueryRowContext(ctx, `
+			SELECT table_name
+			FROM information_schema.tables
+			WHERE table_schema = 'public'
+			AND table_name = 'goose_connection_test'
+		`).Scan(&tableName)
+		c.Assert(err, qt.IsNil)
+	})
+
+	c.Run("Unsupported connection", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithConnectionExtractor(func(pgdbtemplate.DatabaseConnection) (*sql.DB, bool) {
+				return nil, false
+			}),
+		)
+
+		err := runner.RunMigrations(ctx, &opaqueConnection{})
+		c.Assert(err, qt.ErrorMatches, "goose adapter requires database/sql connection: .*got \\*pgdbtemplategoose_test.opaqueConnection")
+	})
+}
+
+func TestMigrationRunnerLifecycle(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	migrations := map[string]string{
+		"00001_create_table.sql": `-- +goose Up
+CREATE TABLE goose_lifecycle_test (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE goose_lifecycle_test;
+`,
+	}
+
+	// createTestDatabase creates a fresh database using the given provider.
+	createTestDatabase := func(c *qt.C, provider pgdbtemplate.ConnectionProvider) pgdbtemplate.DatabaseConnection {
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    &pgdbtemplate.NoOpMigrationRunner{},
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+
+		conn, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() {
+			conn.Close()
+			tm.DropTestDatabase(ctx, dbName)
+		})
+		return conn
+	}
+
+	c.Run("Pgx connections are released after each run", func(c *qt.C) {
+		c.Parallel()
+
+		provider := pgdbtemplatepgx.NewConnectionProvider(testConnectionStringFunc)
+		defer provider.Close()
+
+		conn := createTestDatabase(c, provider)
+		pgxConn := conn.(*pgdbtemplatepgx.DatabaseConnection)
+
+		runner := pgdbtemplategoose.NewMigrationRunner(writeMigrations(c, migrations))
+		defer runner.Close()
+
+		// Repeated runs must not accumulate connections.
+		for i := 0; i < 20; i++ {
+			err := runner.RunMigrations(ctx, conn)
+			c.Assert(err, qt.IsNil)
+			c.Assert(pgxConn.Pool.Stat().AcquiredConns(), qt.Equals, int32(0))
+		}
+		c.Assert(pgxConn.Pool.Stat().TotalConns() <= pgxConn.Pool.Stat().MaxConns(), qt.IsTrue)
+
+		// The pool itself must stay usable.
+		err := pgxConn.Pool.Ping(ctx)
+		c.Assert(err, qt.IsNil)
+	})
+
+	c.Run("Pq connections are left open", func(c *qt.C) {
+		c.Parallel()
+
+		conn := createTestDatabase(c, pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc))
+		pqConn := conn.(*pgdbtemplatepq.DatabaseConnection)
+
+		runner := pgdbtemplategoose.NewMigrationRunner(writeMigrations(c, migrations))
+		defer runner.Close()
+
+		err := runner.RunMigrations(ctx, conn)
+		c.Assert(err, qt.IsNil)
+
+		// The database belongs to the connection and must not be closed.
+		err = pqConn.DB.PingContext(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Assert(pqConn.DB.Stats().InUse, qt.Equals, 0)
+	})
+
+	c.Run("Closed runner", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(writeMigrations(c, migrations))
+		c.Assert(runner.Close(), qt.IsNil)
+		c.Assert(runner.Close(), qt.IsNil)
+
+		err := runner.RunMigrations(ctx, &opaqueConnection{})
+		c.Assert(errors.Is(err, pgdbtemplategoose.ErrRunnerClosed), qt.IsTrue)
+	})
+}
@@ -0,0 +1,164 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	"github.com/pressly/goose/v3"
+)
+
+// PendingMigration is a migration RunMigrations would apply.
+type PendingMigration struct {
+	Version int64
+	Type    goose.MigrationType
+	// Path is the file of the migration. It is empty for Go migrations
+	// registered on the runner.
+	Path string
+	// Source is the name of the source the migration comes from, see
+	// WithMigrationSources. It is empty for migrations from migrationsFs.
+	Source string
+	// UseTx reports whether the migration would run in a transaction.
+	// It is only set for SQL migrations with DryRunStatements.
+	UseTx bool
+	// Statements are the SQL statements of the Up section, after goose
+	// annotation parsing and ENVSUB expansion. They are only set
+	// for SQL migrations with DryRunStatements.
+	Statements []string
+}
+
+// DryRunOption configures DryRun.
+type DryRunOption func(*dryRunConfig)
+
+type dryRunConfig struct {
+	statements bool
+}
+
+// DryRunStatements makes DryRun parse SQL migrations
+// and return the statements that would be executed.
+func DryRunStatements() DryRunOption {
+	return func(c *dryRunConfig) {
+		c.statements = true
+	}
+}
+
+// DryRun returns the migrations RunMigrations would apply to the database,
+// in the order it would apply them, without modifying the database.
+// Like RunMigrations, it stops at the target version set with WithTargetVersion
+// and fails on invalid target versions and missing (out-of-order) migrations.
+//
+// Example:
+//
+//	pending, err := runner.DryRun(ctx, conn, pgdbtemplategoose.DryRunStatements())
+//	if err != nil {
+//	    return err
+//	}
+//	for _, m := range pending {
+//	    fmt.Println(m.Version, m.Path, len(m.Statements))
+//	}
+func (r *MigrationRunner) DryRun(ctx context.Context, conn pgdbtemplate.DatabaseConnection, options ...DryRunOption) (_ []PendingMigration, err error) {
+	config := &dryRunConfig{}
+	for _, opt := range options {
+		opt(config)
+	}
+
+	if r.isClosed() {
+		return nil, ErrRunnerClosed
+	}
+	if err := r.preflight(); err != nil {
+		return nil, err
+	}
+	if len(r.schemas) > 0 {
+		return nil, errors.New("dry run is not supported with per-schema runs")
+	}
+
+	db, release, err := r.extractSQLDB(conn)
+	if err != nil {
+		return nil, fmt.Errorf("goose adapter requires database/sql connection: %w", err)
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release database: %w", releaseErr))
+		}
+	}()
+
+	opts, err := r.providerOptions(r.newRunObserver(ctx, nil, ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goose provider: %w", err)
+	}
+	provider, err := r.newProvider(db, r.versionTable(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goose provider: %w", err)
+	}
+
+	versions, err := r.dryRunVersions(ctx, db, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[int64]*goose.Source)
+	for _, source := range provider.ListSources() {
+		sources[source.Version] = source
+	}
+
+	pending := make([]PendingMigration, 0, len(versions))
+	for _, version := range versions {
+		source := sources[version]
+		migration := PendingMigration{
+			Version: source.Version,
+			Type:    source.Type,
+			Path:    source.Path,
+			Source:  r.migrationSourceName(source.Path),
+		}
+		if config.statements && source.Type == goose.TypeSQL {
+			parsed, err := parseSQLMigrationFile(r.migrationsFs, source.Path)
+			if err != nil {
+				return nil, err
+			}
+			migration.UseTx = parsed.useTx
+			for _, stmt := range parsed.up {
+				migration.Statements = append(migration.Statements, stmt.sql)
+			}
+		}
+		pending = append(pending, migration)
+	}
+	return pending, nil
+}
+
+// dryRunVersions returns the versions RunMigrations would apply.
+//
+// goose creates its version table when asked for the status,
+// so databases without one are handled here: all migrations are pending.
+func (r *MigrationRunner) dryRunVersions(ctx context.Context, db *sql.DB, provider *goose.Provider) ([]int64, error) {
+	exists, err := versionTableExists(ctx, db, r.versionTable())
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return r.pendingVersions(ctx, provider)
+	}
+
+	sources := provider.ListSources()
+	if err := r.checkTargetVersion(sources); err != nil {
+		return nil, err
+	}
+	var versions []int64
+	for _, source := range sources {
+		if r.targetVersion > 0 && source.Version > r.targetVersion {
+			break
+		}
+		versions = append(versions, source.Version)
+	}
+	return versions, nil
+}
+
+// versionTableExists reports whether goose's version table exists.
+func versionTableExists(ctx context.Context, db *sql.DB, versionTable string) (bool, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", versionTable).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check if version table exists: %w", err)
+	}
+	return exists, nil
+}
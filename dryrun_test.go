@@ -0,0 +1,169 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+	"github.com/pressly/goose/v3"
+)
+
+func TestMigrationRunnerDryRun(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	migrations := map[string]string{
+		"00001_create_tasks.sql": `-- +goose Up
+CREATE TABLE goose_dryrun_tasks (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE goose_dryrun_tasks;
+`,
+		"00002_add_title.sql": `-- +goose Up
+ALTER TABLE goose_dryrun_tasks ADD COLUMN title TEXT;
+-- +goose StatementBegin
+CREATE FUNCTION goose_dryrun_count() RETURNS bigint AS $$
+BEGIN
+    RETURN (SELECT COUNT(*) FROM goose_dryrun_tasks);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION goose_dryrun_count();
+ALTER TABLE goose_dryrun_tasks DROP COLUMN title;
+`,
+		"00003_index_title.sql": `-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY goose_dryrun_tasks_title_idx ON goose_dryrun_tasks (title);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY goose_dryrun_tasks_title_idx;
+`,
+	}
+
+	// createTestDatabase returns an empty test database.
+	createTestDatabase := func(c *qt.C) *pgdbtemplatepq.DatabaseConnection {
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+			MigrationRunner:    &pgdbtemplate.NoOpMigrationRunner{},
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() {
+			testDB.Close()
+			tm.DropTestDatabase(ctx, dbName)
+		})
+		return testDB.(*pgdbtemplatepq.DatabaseConnection)
+	}
+
+	c.Run("Fresh database is not modified", func(c *qt.C) {
+		c.Parallel()
+
+		testDB := createTestDatabase(c)
+		runner := pgdbtemplategoose.NewMigrationRunner(writeMigrations(c, migrations))
+
+		pending, err := runner.DryRun(ctx, testDB)
+		c.Assert(err, qt.IsNil)
+		c.Assert(pending, qt.DeepEquals, []pgdbtemplategoose.PendingMigration{
+			{Version: 1, Type: goose.TypeSQL, Path: "00001_create_tasks.sql"},
+			{Version: 2, Type: goose.TypeSQL, Path: "00002_add_title.sql"},
+			{Version: 3, Type: goose.TypeSQL, Path: "00003_index_title.sql"},
+		})
+
+		var exists bool
+		err = testDB.QueryRowContext(ctx, "SELECT to_regclass('goose_db_version') IS NOT NULL").Scan(&exists)
+		c.Assert(err, qt.IsNil)
+		c.Assert(exists, qt.IsFalse)
+	})
+
+	c.Run("Partially migrated database with statements", func(c *qt.C) {
+		c.Parallel()
+
+		testDB := createTestDatabase(c)
+		err := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithTargetVersion(1),
+		).RunMigrations(ctx, testDB)
+		c.Assert(err, qt.IsNil)
+
+		runner := pgdbtemplategoose.NewMigrationRunner(writeMigrations(c, migrations))
+		pending, err := runner.DryRun(ctx, testDB, pgdbtemplategoose.DryRunStatements())
+		c.Assert(err, qt.IsNil)
+		c.Assert(pending, qt.DeepEquals, []pgdbtemplategoose.PendingMigration{{
+			Version: 2,
+			Type:    goose.TypeSQL,
+			Path:    "00002_add_title.sql",
+			UseTx:   true,
+			Statements: []string{
+				"ALTER TABLE goose_dryrun_tasks ADD COLUMN title TEXT;",
+				`CREATE FUNCTION goose_dryrun_count() RETURNS bigint AS $$
+BEGIN
+    RETURN (SELECT COUNT(*) FROM goose_dryrun_tasks);
+END;
+$$ LANGUAGE plpgsql;`,
+			},
+		}, {
+			Version:    3,
+			Type:       goose.TypeSQL,
+			Path:       "00003_index_title.sql",
+			Statements: []string{"CREATE INDEX CONCURRENTLY goose_dryrun_tasks_title_idx ON goose_dryrun_tasks (title);"},
+		}})
+
+		// Nothing was applied.
+		pending, err = runner.DryRun(ctx, testDB)
+		c.Assert(err, qt.IsNil)
+		c.Assert(pending, qt.HasLen, 2)
+	})
+
+	c.Run("Target version", func(c *qt.C) {
+		c.Parallel()
+
+		testDB := createTestDatabase(c)
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithTargetVersion(2),
+		)
+		pending, err := runner.DryRun(ctx, testDB)
+		c.Assert(err, qt.IsNil)
+		c.Assert(pending, qt.HasLen, 2)
+
+		runner = pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithTargetVersion(42),
+		)
+		_, err = runner.DryRun(ctx, testDB)
+		c.Assert(err, qt.ErrorIs, goose.ErrVersionNotFound)
+	})
+
+	c.Run("Closed runner", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(writeMigrations(c, migrations))
+		c.Assert(runner.Close(), qt.IsNil)
+
+		_, err := runner.DryRun(ctx, &sqlDBConnection{})
+		c.Assert(err, qt.ErrorIs, pgdbtemplategoose.ErrRunnerClosed)
+	})
+
+	c.Run("Invalid version table", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithTableName("Schema Migrations"),
+		)
+		_, err := runner.DryRun(ctx, &sqlDBConnection{})
+		c.Assert(err, qt.ErrorMatches, `invalid version table name "Schema Migrations": must be a lower-case unquoted identifier`)
+	})
+}
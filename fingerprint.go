@@ -0,0 +1,100 @@
+package pgdbtemplategoose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+// Fingerprint returns a deterministic hash of everything that defines
+// the schema the runner builds: the dialect, the target version,
+// the version table name and schema, the schemas of per-schema runs in order,
+// the names and contents of all migration and seed files, and the versions
+// and transaction modes of Go migrations registered on the runner.
+//
+// The fingerprint is a hex-encoded SHA-256 sum. It changes whenever any of
+// the above changes, so it can be used to name or tag template databases by
+// content and to detect stale ones. Options passed with WithGooseOptions
+// are opaque and therefore not included.
+//
+// Example:
+//
+//	fingerprint, err := runner.Fingerprint()
+//	if err != nil {
+//	    return err
+//	}
+//	config := pgdbtemplate.Config{
+//	    ConnectionProvider: provider,
+//	    MigrationRunner:    runner,
+//	    TemplateName:       "template_" + fingerprint[:16],
+//	}
+func (r *MigrationRunner) Fingerprint() (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "dialect %q\n", r.dialect)
+	fmt.Fprintf(h, "target %d\n", r.targetVersion)
+	fmt.Fprintf(h, "table %q\n", r.versionTable())
+	for _, schema := range r.schemas {
+		fmt.Fprintf(h, "schema %q\n", schema)
+	}
+
+	if err := hashSources(h, r.migrationsFs); err != nil {
+		return "", err
+	}
+	if err := hashSeeds(h, r.seedsFs); err != nil {
+		return "", err
+	}
+
+	// Go migrations are identified by version and transaction mode only:
+	// functions cannot be hashed.
+	goMigrations := append([]goMigration(nil), r.goMigrations...)
+	sort.Slice(goMigrations, func(i, j int) bool {
+		return goMigrations[i].version < goMigrations[j].version
+	})
+	for _, m := range goMigrations {
+		fmt.Fprintf(h, "go %d %s %s\n", m.version, m.up.Mode, m.down.Mode)
+	}
+	fmt.Fprintf(h, "global registry disabled %t\n", r.disableGlobalRegistry || len(r.goMigrations) > 0)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashSources writes names and contents of migration files in fsys to w.
+func hashSources(w io.Writer, fsys fs.FS) error {
+	sources, err := collectSources(fsys)
+	if err != nil {
+		return fmt.Errorf("failed to collect migrations: %w", err)
+	}
+	for _, source := range sources {
+		content, err := fs.ReadFile(fsys, source.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", source.Path, err)
+		}
+		// Length-prefix contents so that file boundaries are unambiguous.
+		fmt.Fprintf(w, "file %q %d\n", source.Path, len(content))
+		w.Write(content) // #nosec G104 -- hash writes never fail.
+	}
+	return nil
+}
+
+// hashSeeds writes names and contents of seed files in fsys to w.
+func hashSeeds(w io.Writer, fsys fs.FS) error {
+	if fsys == nil {
+		return nil
+	}
+	files, err := collectSeedFiles(fsys)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("failed to read seed %s: %w", file, err)
+		}
+		fmt.Fprintf(w, "seed %q %d\n", file, len(content))
+		w.Write(content) // #nosec G104 -- hash writes never fail.
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	qt "github.com/frankban/quicktest"
+	"github.com/pressly/goose/v3"
+)
+
+func TestMigrationRunnerFingerprint(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	newFs := func() fstest.MapFS {
+		return fstest.MapFS{
+			"00001_create_users.sql": {Data: []byte("-- +goose Up\nCREATE TABLE users (id int);\n")},
+			"00002_create_posts.sql": {Data: []byte("-- +goose Up\nCREATE TABLE posts (id int);\n")},
+		}
+	}
+	fingerprint := func(c *qt.C, runner *pgdbtemplategoose.MigrationRunner) string {
+		fp, err := runner.Fingerprint()
+		c.Assert(err, qt.IsNil)
+		return fp
+	}
+	noopTx := func(context.Context, *sql.Tx) error { return nil }
+	noopDB := func(context.Context, *sql.DB) error { return nil }
+
+	base := fingerprint(c, pgdbtemplategoose.NewMigrationRunner(newFs()))
+	c.Assert(base, qt.HasLen, 64)
+
+	c.Run("Deterministic", func(c *qt.C) {
+		c.Parallel()
+
+		c.Assert(fingerprint(c, pgdbtemplategoose.NewMigrationRunner(newFs())), qt.Equals, base)
+	})
+
+	c.Run("Ignores non-migration files", func(c *qt.C) {
+		c.Parallel()
+
+		fsys := newFs()
+		fsys["README.md"] = &fstest.MapFile{Data: []byte("docs")}
+		fsys["helpers.go"] = &fstest.MapFile{Data: []byte("package migrations")}
+		c.Assert(fingerprint(c, pgdbtemplategoose.NewMigrationRunner(fsys)), qt.Equals, base)
+	})
+
+	c.Run("Changes with migrations", func(c *qt.C) {
+		c.Parallel()
+
+		changedContent := newFs()
+		changedContent["00002_create_posts.sql"] = &fstest.MapFile{Data: []byte("-- +goose Up\nCREATE TABLE posts (id bigint);\n")}
+
+		renamed := newFs()
+		renamed["00003_create_posts.sql"] = renamed["00002_create_posts.sql"]
+		delete(renamed, "00002_create_posts.sql")
+
+		added := newFs()
+		added["00003_create_tags.sql"] = &fstest.MapFile{Data: []byte("-- +goose Up\nCREATE TABLE tags (id int);\n")}
+
+		seen := map[string]bool{base: true}
+		for _, runner := range []*pgdbtemplategoose.MigrationRunner{
+			pgdbtemplategoose.NewMigrationRunner(changedContent),
+			pgdbtemplategoose.NewMigrationRunner(renamed),
+			pgdbtemplategoose.NewMigrationRunner(added),
+			pgdbtemplategoose.NewMigrationRunner(newFs(), pgdbtemplategoose.WithTargetVersion(1)),
+			pgdbtemplategoose.NewMigrationRunner(newFs(), pgdbtemplategoose.WithDialect(goose.DialectMySQL)),
+			pgdbtemplategoose.NewMigrationRunner(newFs(), pgdbtemplategoose.WithTableName("schema_migrations")),
+			pgdbtemplategoose.NewMigrationRunner(newFs(), pgdbtemplategoose.WithTableSchema("ops")),
+			pgdbtemplategoose.NewMigrationRunner(newFs(), pgdbtemplategoose.WithSchemas("tenant_a", "tenant_b")),
+			pgdbtemplategoose.NewMigrationRunner(newFs(), pgdbtemplategoose.WithSchemas("tenant_b", "tenant_a")),
+			pgdbtemplategoose.NewMigrationRunner(newFs(), pgdbtemplategoose.WithGoMigration(3, noopTx, nil)),
+			pgdbtemplategoose.NewMigrationRunner(newFs(), pgdbtemplategoose.WithGoMigrationNoTx(3, noopDB, nil)),
+			pgdbtemplategoose.NewMigrationRunner(newFs(), pgdbtemplategoose.WithGoMigration(4, noopTx, nil)),
+			pgdbtemplategoose.NewMigrationRunner(newFs(), pgdbtemplategoose.WithSeeds(fstest.MapFS{
+				"roles.yaml": {Data: []byte("roles:\n  - name: admin\n")},
+			})),
+		} {
+			fp := fingerprint(c, runner)
+			c.Assert(seen[fp], qt.IsFalse, qt.Commentf("duplicate fingerprint %s", fp))
+			seen[fp] = true
+		}
+	})
+
+	c.Run("Go migration registration order", func(c *qt.C) {
+		c.Parallel()
+
+		first := pgdbtemplategoose.NewMigrationRunner(newFs(),
+			pgdbtemplategoose.WithGoMigration(3, noopTx, nil),
+			pgdbtemplategoose.WithGoMigration(4, noopTx, nil),
+		)
+		second := pgdbtemplategoose.NewMigrationRunner(newFs(),
+			pgdbtemplategoose.WithGoMigration(4, noopTx, nil),
+			pgdbtemplategoose.WithGoMigration(3, noopTx, nil),
+		)
+		c.Assert(fingerprint(c, first), qt.Equals, fingerprint(c, second))
+	})
+
+	c.Run("Duplicate versions", func(c *qt.C) {
+		c.Parallel()
+
+		fsys := newFs()
+		fsys["00002_duplicate.sql"] = &fstest.MapFile{Data: []byte("-- +goose Up\n")}
+		_, err := pgdbtemplategoose.NewMigrationRunner(fsys).Fingerprint()
+		c.Assert(err, qt.ErrorMatches, "failed to collect migrations: found duplicate migration version 2: .*")
+	})
+}
@@ -0,0 +1,131 @@
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/andrei-polukhin/pgdbtemplate-goose/internal/fixture"
+)
+
+// sortTables orders tables so that tables referenced by foreign keys
+// are inserted before the tables referencing them. Tables without
+// dependencies between them keep their order.
+func sortTables(ctx context.Context, tx *sql.Tx, tables []fixture.Table) ([]fixture.Table, error) {
+	oids := make([]int64, len(tables))
+	oidIndex := make(map[int64]int, len(tables))
+	for i, table := range tables {
+		var oid sql.NullInt64
+		err := tx.QueryRowContext(ctx, "SELECT to_regclass($1)::oid", fixture.QuoteQualifiedIdentifier(table.Name)).Scan(&oid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve table %s: %w", table.Name, err)
+		}
+		if !oid.Valid {
+			return nil, fmt.Errorf("table %s does not exist", table.Name)
+		}
+		oids[i] = oid.Int64
+		oidIndex[oid.Int64] = i
+	}
+
+	// dependencies[i] are indexes of tables table i references.
+	dependencies := make([][]int, len(tables))
+	rows, err := tx.QueryContext(ctx, "SELECT conrelid::oid, confrelid::oid FROM pg_constraint WHERE contype = 'f'")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var child, parent int64
+		if err := rows.Scan(&child, &parent); err != nil {
+			return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+		}
+		childIndex, childOK := oidIndex[child]
+		parentIndex, parentOK := oidIndex[parent]
+		// Self-references are satisfied by row order within the table.
+		if childOK && parentOK && child != parent {
+			dependencies[childIndex] = append(dependencies[childIndex], parentIndex)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+
+	// Depth-first topological sort, visiting tables in their original order.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(tables))
+	sorted := make([]fixture.Table, 0, len(tables))
+	var path []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("foreign keys between fixture tables form a cycle: %s -> %s",
+				strings.Join(path, " -> "), tables[i].Name)
+		}
+		state[i] = visiting
+		path = append(path, tables[i].Name)
+		for _, dependency := range dependencies[i] {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		sorted = append(sorted, tables[i])
+		return nil
+	}
+	for i := range tables {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// resetSequences moves serial and identity sequences of the table past
+// the largest value of their columns.
+func resetSequences(ctx context.Context, tx *sql.Tx, table string) error {
+	quotedTable := fixture.QuoteQualifiedIdentifier(table)
+	rows, err := tx.QueryContext(ctx, `
+		SELECT attname, pg_get_serial_sequence($1::text, attname)
+		FROM pg_attribute
+		WHERE attrelid = $1::text::regclass
+		AND attnum > 0
+		AND NOT attisdropped
+		AND pg_get_serial_sequence($1::text, attname) IS NOT NULL`,
+		quotedTable,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to list sequences of %s: %w", table, err)
+	}
+
+	sequences := make(map[string]string)
+	for rows.Next() {
+		var column, sequence string
+		if err := rows.Scan(&column, &sequence); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to list sequences of %s: %w", table, err)
+		}
+		sequences[column] = sequence
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list sequences of %s: %w", table, err)
+	}
+
+	for column, sequence := range sequences {
+		query := fmt.Sprintf("SELECT setval($1, COALESCE((SELECT MAX(%s) FROM %s), 0) + 1, false)",
+			fixture.QuoteIdentifier(column), quotedTable)
+		if _, err := tx.ExecContext(ctx, query, sequence); err != nil {
+			return fmt.Errorf("failed to reset sequence %s: %w", sequence, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+// Package fixtures loads per-test datasets into databases
+// created from pgdbtemplate templates.
+//
+// Fixture files are YAML or JSON documents mapping table names to lists
+// of rows, in the same format as template seeds (see
+// pgdbtemplategoose.WithSeeds):
+//
+//	users:
+//	  - id: 1
+//	    email: alice@example.com
+//	orders:
+//	  - user_id: 1
+//	    total: 42
+//
+// Tables are inserted in foreign-key order resolved from the catalog,
+// regardless of their order in the files, and serial and identity sequences
+// are reset afterwards, so that rows inserted by the test itself do not
+// collide with fixture rows.
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	"github.com/andrei-polukhin/pgdbtemplate-goose/internal/fixture"
+	"github.com/andrei-polukhin/pgdbtemplate-goose/internal/sqldb"
+)
+
+// fixtureExtensions are tried in order for names without an extension.
+var fixtureExtensions = []string{".yaml", ".yml", ".json"}
+
+// Loader loads named fixture files into test databases.
+type Loader struct {
+	fixturesFs fs.FS
+	extractors []sqldb.Extractor
+}
+
+// Option configures a Loader.
+type Option func(*Loader)
+
+// WithConnectionExtractor registers extractors of *sql.DB from custom
+// connections, tried before the built-in ones, like
+// pgdbtemplategoose.WithConnectionExtractor does for the runner.
+//
+// Example:
+//
+//	loader := fixtures.NewLoader(
+//	    fixturesFS,
+//	    fixtures.WithConnectionExtractor(extractTracedDB),
+//	)
+func WithConnectionExtractor(extractors ...pgdbtemplategoose.ConnectionExtractor) Option {
+	return func(l *Loader) {
+		for _, extractor := range extractors {
+			l.extractors = append(l.extractors, sqldb.Extractor(extractor))
+		}
+	}
+}
+
+// NewLoader creates a loader of fixture files in fixturesFs.
+//
+// Example:
+//
+//	//go:embed testdata/fixtures
+//	var fixturesFS embed.FS
+//	loader := fixtures.NewLoader(fixturesFS)
+//
+//	testDB, dbName, err := tm.CreateTestDatabase(ctx)
+//	...
+//	err = loader.Load(ctx, testDB, "testdata/fixtures/users", "testdata/fixtures/orders.yaml")
+func NewLoader(fixturesFs fs.FS, options ...Option) *Loader {
+	loader := &Loader{fixturesFs: fixturesFs}
+	for _, opt := range options {
+		opt(loader)
+	}
+	return loader
+}
+
+// Load loads the named fixture files into the database in a single transaction.
+//
+// Names without an extension are looked up with .yaml, .yml and .json
+// extensions, in that order. Rows of a table listed in several files are
+// inserted in the order of the files.
+//
+// Supports pgdbtemplate-pq, pgdbtemplate-pgx and
+// pgdbtemplategoose.SQLDBConnection connections, as well as connections
+// supported by extractors registered with WithConnectionExtractor.
+func (l *Loader) Load(ctx context.Context, conn pgdbtemplate.DatabaseConnection, names ...string) (err error) {
+	tables, err := l.readFixtures(names)
+	if err != nil {
+		return err
+	}
+
+	db, opened, err := sqldb.Extract(conn, l.extractors...)
+	if err != nil {
+		return err
+	}
+	if opened {
+		defer func() {
+			if closeErr := db.Close(); closeErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to release database: %w", closeErr))
+			}
+		}()
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin fixtures transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	if tables, err = sortTables(ctx, tx, tables); err != nil {
+		return err
+	}
+	for _, table := range tables {
+		for i, row := range table.Rows {
+			if _, err = tx.ExecContext(ctx, fixture.InsertQuery(table.Name, row), row.Values...); err != nil {
+				return fmt.Errorf("failed to insert row %d into %s: %w", i+1, table.Name, err)
+			}
+		}
+	}
+	for _, table := range tables {
+		if err = resetSequences(ctx, tx, table.Name); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit fixtures: %w", err)
+	}
+	return nil
+}
+
+// readFixtures parses the named files and merges rows of the same table.
+func (l *Loader) readFixtures(names []string) ([]fixture.Table, error) {
+	var tables []fixture.Table
+	index := make(map[string]int)
+	for _, name := range names {
+		content, err := l.readFile(name)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := fixture.Parse(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse fixture %s: %w", name, err)
+		}
+
+		for _, table := range parsed {
+			if i, ok := index[table.Name]; ok {
+				tables[i].Rows = append(tables[i].Rows, table.Rows...)
+				continue
+			}
+			index[table.Name] = len(tables)
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// readFile reads a fixture file, trying known extensions
+// if the name has none.
+func (l *Loader) readFile(name string) ([]byte, error) {
+	if l.fixturesFs == nil {
+		return nil, errors.New("fixtures file system is nil")
+	}
+	if path.Ext(name) != "" {
+		content, err := fs.ReadFile(l.fixturesFs, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture %s: %w", name, err)
+		}
+		return content, nil
+	}
+
+	for _, ext := range fixtureExtensions {
+		content, err := fs.ReadFile(l.fixturesFs, name+ext)
+		if err == nil {
+			return content, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read fixture %s: %w", name+ext, err)
+		}
+	}
+	return nil, fmt.Errorf("failed to read fixture %s: %w", name, fs.ErrNotExist)
+}
@@ -0,0 +1,181 @@
+package fixtures_test
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	"github.com/andrei-polukhin/pgdbtemplate-goose/fixtures"
+	pgdbtemplatepgx "github.com/andrei-polukhin/pgdbtemplate-pgx"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+// testConnectionStringFunc creates a connection string for tests.
+func testConnectionStringFunc(dbName string) string {
+	return pgdbtemplate.ReplaceDatabaseInConnectionString(testConnectionString, dbName)
+}
+
+var migrationsFs = fstest.MapFS{
+	"00001_create_shop.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE fixtures_users (
+    id SERIAL PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    referrer_id INTEGER REFERENCES fixtures_users (id)
+);
+CREATE TABLE fixtures_orders (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES fixtures_users (id),
+    items JSONB NOT NULL DEFAULT '[]'
+);
+CREATE TABLE fixtures_a (id INTEGER PRIMARY KEY, b_id INTEGER);
+CREATE TABLE fixtures_b (id INTEGER PRIMARY KEY, a_id INTEGER REFERENCES fixtures_a (id));
+ALTER TABLE fixtures_a ADD FOREIGN KEY (b_id) REFERENCES fixtures_b (id);
+
+-- +goose Down
+DROP TABLE fixtures_a, fixtures_b, fixtures_orders, fixtures_users CASCADE;
+`)},
+}
+
+var fixturesFs = fstest.MapFS{
+	// Orders come first on purpose: the loader resolves the order.
+	"orders.yaml": {Data: []byte(`fixtures_orders:
+  - id: 10
+    user_id: 2
+    items: [{sku: apple, quantity: 3}]
+`)},
+	"users.json": {Data: []byte(`{"fixtures_users": [
+  {"id": 1, "email": "alice@example.com"},
+  {"id": 2, "email": "bob@example.com", "referrer_id": 1}
+]}`)},
+	"cycle.yaml": {Data: []byte(`fixtures_a:
+  - id: 1
+fixtures_b:
+  - id: 1
+`)},
+	"unknown.yaml": {Data: []byte("fixtures_unknown:\n  - id: 1\n")},
+}
+
+// wrappedConnection is a connection the runner does not recognise
+// out of the box.
+type wrappedConnection struct {
+	pgdbtemplate.DatabaseConnection
+}
+
+func TestLoader(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	// createTestDatabase returns a migrated test database.
+	createTestDatabase := func(c *qt.C, provider pgdbtemplate.ConnectionProvider) pgdbtemplate.DatabaseConnection {
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    pgdbtemplategoose.NewMigrationRunner(migrationsFs),
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() {
+			testDB.Close()
+			tm.DropTestDatabase(ctx, dbName)
+		})
+		return testDB
+	}
+
+	loader := fixtures.NewLoader(fixturesFs)
+
+	c.Run("Foreign key order and sequences", func(c *qt.C) {
+		c.Parallel()
+
+		testDB := createTestDatabase(c, pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc))
+		err := loader.Load(ctx, testDB, "orders", "users.json")
+		c.Assert(err, qt.IsNil)
+
+		db := testDB.(*pgdbtemplatepq.DatabaseConnection).DB
+
+		var items string
+		err = db.QueryRowContext(ctx, "SELECT items::text FROM fixtures_orders WHERE id = 10").Scan(&items)
+		c.Assert(err, qt.IsNil)
+		c.Assert(items, qt.Equals, `[{"sku": "apple", "quantity": 3}]`)
+
+		// Sequences continue after fixture rows.
+		var userID, orderID int64
+		err = db.QueryRowContext(ctx, "INSERT INTO fixtures_users (email) VALUES ('carol@example.com') RETURNING id").Scan(&userID)
+		c.Assert(err, qt.IsNil)
+		c.Assert(userID, qt.Equals, int64(3))
+		err = db.QueryRowContext(ctx, "INSERT INTO fixtures_orders (user_id) VALUES (3) RETURNING id").Scan(&orderID)
+		c.Assert(err, qt.IsNil)
+		c.Assert(orderID, qt.Equals, int64(11))
+	})
+
+	c.Run("pgx connection", func(c *qt.C) {
+		c.Parallel()
+
+		provider := pgdbtemplatepgx.NewConnectionProvider(testConnectionStringFunc)
+		c.Cleanup(provider.Close)
+
+		testDB := createTestDatabase(c, provider)
+		err := loader.Load(ctx, testDB, "users")
+		c.Assert(err, qt.IsNil)
+
+		var count int
+		err = testDB.(*pgdbtemplatepgx.DatabaseConnection).Pool.QueryRow(ctx, "SELECT COUNT(*) FROM fixtures_users").Scan(&count)
+		c.Assert(err, qt.IsNil)
+		c.Assert(count, qt.Equals, 2)
+	})
+
+	c.Run("Connection extractor", func(c *qt.C) {
+		c.Parallel()
+
+		testDB := createTestDatabase(c, pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc))
+		wrapped := &wrappedConnection{DatabaseConnection: testDB}
+
+		extractor := func(conn pgdbtemplate.DatabaseConnection) (*sql.DB, bool) {
+			w, ok := conn.(*wrappedConnection)
+			if !ok {
+				return nil, false
+			}
+			return w.DatabaseConnection.(*pgdbtemplatepq.DatabaseConnection).DB, true
+		}
+		err := fixtures.NewLoader(fixturesFs).Load(ctx, wrapped, "users")
+		c.Assert(err, qt.ErrorMatches, "goose adapter requires .*")
+
+		err = fixtures.NewLoader(fixturesFs, fixtures.WithConnectionExtractor(extractor)).Load(ctx, wrapped, "users")
+		c.Assert(err, qt.IsNil)
+
+		var count int
+		err = testDB.(*pgdbtemplatepq.DatabaseConnection).DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM fixtures_users").Scan(&count)
+		c.Assert(err, qt.IsNil)
+		c.Assert(count, qt.Equals, 2)
+	})
+
+	c.Run("Errors roll back", func(c *qt.C) {
+		c.Parallel()
+
+		testDB := createTestDatabase(c, pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc))
+
+		err := loader.Load(ctx, testDB, "users", "cycle")
+		c.Assert(err, qt.ErrorMatches, "foreign keys between fixture tables form a cycle: fixtures_a -> fixtures_b -> fixtures_a")
+
+		err = loader.Load(ctx, testDB, "users", "unknown")
+		c.Assert(err, qt.ErrorMatches, "table fixtures_unknown does not exist")
+
+		err = loader.Load(ctx, testDB, "missing")
+		c.Assert(err, qt.ErrorIs, fs.ErrNotExist)
+
+		var count int
+		err = testDB.(*pgdbtemplatepq.DatabaseConnection).DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM fixtures_users").Scan(&count)
+		c.Assert(err, qt.IsNil)
+		c.Assert(count, qt.Equals, 0)
+	})
+}
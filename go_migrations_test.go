@@ -0,0 +1,168 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+	"github.com/pressly/goose/v3"
+)
+
+func TestMigrationRunnerGoMigrations(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	c.Run("Tx and no-tx Go migrations", func(c *qt.C) {
+		c.Parallel()
+
+		migrationsFs := writeMigrations(c, map[string]string{
+			"00001_create_roles.sql": `-- +goose Up
+CREATE TABLE goose_go_roles (id SERIAL PRIMARY KEY, name TEXT NOT NULL);
+
+-- +goose Down
+DROP TABLE goose_go_roles;
+`,
+		})
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			migrationsFs,
+			pgdbtemplategoose.WithGoMigration(2,
+				func(ctx context.Context, tx *sql.Tx) error {
+					_, err := tx.ExecContext(ctx, "INSERT INTO goose_go_roles (name) VALUES ('admin')")
+					return err
+				},
+				func(ctx context.Context, tx *sql.Tx) error {
+					_, err := tx.ExecContext(ctx, "DELETE FROM goose_go_roles WHERE name = 'admin'")
+					return err
+				},
+			),
+			pgdbtemplategoose.WithGoMigrationNoTx(3,
+				func(ctx context.Context, db *sql.DB) error {
+					_, err := db.ExecContext(ctx, "CREATE INDEX CONCURRENTLY goose_go_roles_name_idx ON goose_go_roles (name)")
+					return err
+				},
+				nil,
+			),
+		)
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+		defer tm.Cleanup(ctx)
+
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		defer testDB.Close()
+		defer tm.DropTestDatabase(ctx, dbName)
+
+		pqConn := testDB.(*pgdbtemplatepq.DatabaseConnection)
+
+		var name string
+		err = pqConn.DB.QueryRowContext(ctx, "SELECT name FROM goose_go_roles").Scan(&name)
+		c.Assert(err, qt.IsNil)
+		c.Assert(name, qt.Equals, "admin")
+
+		var indexName string
+		err = pqConn.DB.QueryRowContext(ctx, `
+			SELECT indexname
+			FROM pg_indexes
+			WHERE tablename = 'goose_go_roles'
+			AND indexname = 'goose_go_roles_name_idx'
+		`).Scan(&indexName)
+		c.Assert(err, qt.IsNil)
+
+		report := runner.LastReport()
+		c.Assert(report.Migrations, qt.HasLen, 3)
+		c.Assert(report.Migrations[1].Type, qt.Equals, goose.TypeGo)
+		c.Assert(report.Migrations[2].Type, qt.Equals, goose.TypeGo)
+	})
+
+	c.Run("Runners are isolated from each other", func(c *qt.C) {
+		c.Parallel()
+
+		// Both runners register a Go migration with the same version.
+		newRunner := func(table string) *pgdbtemplategoose.MigrationRunner {
+			return pgdbtemplategoose.NewMigrationRunner(
+				nil,
+				pgdbtemplategoose.WithGoMigration(1,
+					func(ctx context.Context, tx *sql.Tx) error {
+						_, err := tx.ExecContext(ctx, "CREATE TABLE "+table+" (id SERIAL PRIMARY KEY)")
+						return err
+					},
+					nil,
+				),
+			)
+		}
+
+		for _, table := range []string{"goose_go_isolated_a", "goose_go_isolated_b"} {
+			table := table
+			c.Run(table, func(c *qt.C) {
+				c.Parallel()
+
+				tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+					ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+					MigrationRunner:    newRunner(table),
+				})
+				c.Assert(err, qt.IsNil)
+
+				err = tm.Initialize(ctx)
+				c.Assert(err, qt.IsNil)
+				defer tm.Cleanup(ctx)
+
+				testDB, dbName, err := tm.CreateTestDatabase(ctx)
+				c.Assert(err, qt.IsNil)
+				defer testDB.Close()
+				defer tm.DropTestDatabase(ctx, dbName)
+
+				pqConn := testDB.(*pgdbtemplatepq.DatabaseConnection)
+
+				var count int
+				err = pqConn.DB.QueryRowContext(ctx, `
+					SELECT COUNT(*)
+					FROM information_schema.tables
+					WHERE table_schema = 'public'
+					AND table_name LIKE 'goose_go_isolated_%'
+				`).Scan(&count)
+				c.Assert(err, qt.IsNil)
+				c.Assert(count, qt.Equals, 1)
+			})
+		}
+	})
+
+	c.Run("Duplicate version with SQL migration", func(c *qt.C) {
+		c.Parallel()
+
+		migrationsFs := writeMigrations(c, map[string]string{
+			"00001_create.sql": `-- +goose Up
+CREATE TABLE goose_go_duplicate (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE goose_go_duplicate;
+`,
+		})
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			migrationsFs,
+			pgdbtemplategoose.WithGoMigration(1, nil, nil),
+		)
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.ErrorMatches, "(?s).*failed to create goose provider: found duplicate migration version 1.*")
+	})
+}
@@ -0,0 +1,136 @@
+// Package fixture parses table fixtures shared by template seeds
+// and per-test fixtures.
+package fixture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Table is a set of rows to insert into a table.
+type Table struct {
+	// Name is the table name as written in the fixture,
+	// possibly schema-qualified.
+	Name string
+	Rows []Row
+}
+
+// Row is a single row of a table fixture,
+// with columns in the order they appear in the file.
+type Row struct {
+	Columns []string
+	Values  []any
+}
+
+// Parse parses a YAML or JSON document mapping table names
+// to lists of rows. Tables and columns keep the order of the document,
+// so that tables referenced by foreign keys can be listed first.
+// Nested objects and lists are stored as JSON, e.g. for jsonb columns.
+//
+// Example:
+//
+//	roles:
+//	  - name: admin
+//	    permissions: [read, write]
+//	  - name: viewer
+//	    permissions: [read]
+func Parse(content []byte) ([]Table, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to parse fixtures: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: fixtures must map table names to lists of rows", root.Line)
+	}
+
+	var tables []Table
+	for i := 0; i < len(root.Content); i += 2 {
+		name, rows := root.Content[i], root.Content[i+1]
+		if rows.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("line %d: rows of table %s must be a list", rows.Line, name.Value)
+		}
+
+		table := Table{Name: name.Value}
+		for _, rowNode := range rows.Content {
+			row, err := parseRow(rowNode)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: table %s: %w", rowNode.Line, name.Value, err)
+			}
+			table.Rows = append(table.Rows, row)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// parseRow parses a mapping of column names to values.
+func parseRow(node *yaml.Node) (Row, error) {
+	if node.Kind != yaml.MappingNode {
+		return Row{}, errors.New("row must map column names to values")
+	}
+	if len(node.Content) == 0 {
+		return Row{}, errors.New("row must have at least one column")
+	}
+
+	var row Row
+	for i := 0; i < len(node.Content); i += 2 {
+		var value any
+		if err := node.Content[i+1].Decode(&value); err != nil {
+			return Row{}, fmt.Errorf("column %s: %w", node.Content[i].Value, err)
+		}
+		switch value.(type) {
+		case map[string]any, []any:
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return Row{}, fmt.Errorf("column %s: %w", node.Content[i].Value, err)
+			}
+			value = string(encoded)
+		}
+		row.Columns = append(row.Columns, node.Content[i].Value)
+		row.Values = append(row.Values, value)
+	}
+	return row, nil
+}
+
+// InsertQuery builds a parameterized INSERT statement for the row.
+func InsertQuery(table string, row Row) string {
+	quoted := make([]string, len(row.Columns))
+	placeholders := make([]string, len(row.Columns))
+	for i, column := range row.Columns {
+		quoted[i] = QuoteIdentifier(column)
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		QuoteQualifiedIdentifier(table),
+		strings.Join(quoted, ", "),
+		strings.Join(placeholders, ", "),
+	)
+}
+
+// QuoteQualifiedIdentifier quotes each part of a possibly
+// schema-qualified name, e.g. public.users.
+func QuoteQualifiedIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// QuoteIdentifier quotes a PostgreSQL identifier.
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
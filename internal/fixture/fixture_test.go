@@ -0,0 +1,90 @@
+package fixture
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	c.Run("YAML", func(c *qt.C) {
+		c.Parallel()
+
+		fixtures, err := Parse([]byte(`
+roles:
+  - name: admin
+    permissions: [read, write]
+  - name: viewer
+    enabled: false
+public.countries:
+  - code: DE
+    population: 84000000
+    meta: {eu: true}
+    retired: null
+`))
+		c.Assert(err, qt.IsNil)
+		c.Assert(fixtures, qt.DeepEquals, []Table{{
+			Name: "roles",
+			Rows: []Row{
+				{Columns: []string{"name", "permissions"}, Values: []any{"admin", `["read","write"]`}},
+				{Columns: []string{"name", "enabled"}, Values: []any{"viewer", false}},
+			},
+		}, {
+			Name: "public.countries",
+			Rows: []Row{
+				{Columns: []string{"code", "population", "meta", "retired"}, Values: []any{"DE", 84000000, `{"eu":true}`, nil}},
+			},
+		}})
+	})
+
+	c.Run("JSON", func(c *qt.C) {
+		c.Parallel()
+
+		fixtures, err := Parse([]byte(`{"feature_flags": [{"key": "beta", "enabled": true}]}`))
+		c.Assert(err, qt.IsNil)
+		c.Assert(fixtures, qt.DeepEquals, []Table{{
+			Name: "feature_flags",
+			Rows: []Row{
+				{Columns: []string{"key", "enabled"}, Values: []any{"beta", true}},
+			},
+		}})
+	})
+
+	c.Run("Empty", func(c *qt.C) {
+		c.Parallel()
+
+		fixtures, err := Parse(nil)
+		c.Assert(err, qt.IsNil)
+		c.Assert(fixtures, qt.HasLen, 0)
+	})
+
+	c.Run("Invalid", func(c *qt.C) {
+		c.Parallel()
+
+		for _, test := range []struct {
+			content string
+			err     string
+		}{
+			{"- roles", "line 1: fixtures must map table names to lists of rows"},
+			{"roles: admin", "line 1: rows of table roles must be a list"},
+			{"roles:\n  - admin", "line 2: table roles: row must map column names to values"},
+			{"roles:\n  - {}", "line 2: table roles: row must have at least one column"},
+			{"roles: [", "failed to parse fixtures: .*"},
+		} {
+			_, err := Parse([]byte(test.content))
+			c.Assert(err, qt.ErrorMatches, test.err, qt.Commentf("content %q", test.content))
+		}
+	})
+}
+
+func TestInsertQuery(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	row := Row{Columns: []string{"name", `odd"column`}, Values: []any{"admin", 1}}
+	c.Assert(InsertQuery("public.roles", row), qt.Equals,
+		`INSERT INTO "public"."roles" ("name", "odd""column") VALUES ($1, $2)`)
+}
@@ -0,0 +1,54 @@
+// Package sqldb extracts *sql.DB from pgdbtemplate connections, shared by
+// the migration runner and per-test fixtures.
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplatepgx "github.com/andrei-polukhin/pgdbtemplate-pgx"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Connection is implemented by connections which hand their underlying
+// *sql.DB over, like pgdbtemplategoose.SQLDBConnection.
+type Connection interface {
+	SQLDB() *sql.DB
+}
+
+// Extractor extracts *sql.DB from connections it supports.
+type Extractor func(conn pgdbtemplate.DatabaseConnection) (*sql.DB, bool)
+
+// Extract returns the *sql.DB backing conn.
+//
+// Extractors are tried first, then Connection, then pgdbtemplate-pq and
+// pgdbtemplate-pgx connections. For pgdbtemplate-pgx connections, a
+// database/sql wrapper of the pool is opened and opened is true: the caller
+// owns it and must close it, which returns its connections to the pool
+// without closing the pool.
+func Extract(conn pgdbtemplate.DatabaseConnection, extractors ...Extractor) (db *sql.DB, opened bool, err error) {
+	for _, extractor := range extractors {
+		if db, ok := extractor(conn); ok {
+			return db, false, nil
+		}
+	}
+
+	// Custom connections exposing *sql.DB themselves.
+	if sqlConn, ok := conn.(Connection); ok {
+		return sqlConn.SQLDB(), false, nil
+	}
+
+	// Try pgdbtemplate-pq first (embeds *sql.DB).
+	if pqConn, ok := conn.(*pgdbtemplatepq.DatabaseConnection); ok {
+		return pqConn.DB, false, nil
+	}
+
+	// Try pgdbtemplate-pgx (has Pool field).
+	if pgxConn, ok := conn.(*pgdbtemplatepgx.DatabaseConnection); ok {
+		return stdlib.OpenDBFromPool(pgxConn.Pool), true, nil
+	}
+
+	return nil, false, fmt.Errorf("goose adapter requires pgdbtemplate-pq, pgdbtemplate-pgx or SQLDBConnection connection, got %T", conn)
+}
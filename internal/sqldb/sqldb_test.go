@@ -0,0 +1,63 @@
+package sqldb
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+// sqlDBConnection exposes its *sql.DB through Connection.
+type sqlDBConnection struct {
+	pgdbtemplate.DatabaseConnection
+	db *sql.DB
+}
+
+func (c *sqlDBConnection) SQLDB() *sql.DB { return c.db }
+
+// otherConnection is a connection of an unsupported driver.
+type otherConnection struct {
+	pgdbtemplate.DatabaseConnection
+}
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	pqDB := &sql.DB{}
+	customDB := &sql.DB{}
+	extractedDB := &sql.DB{}
+
+	for _, test := range []struct {
+		about      string
+		conn       pgdbtemplate.DatabaseConnection
+		extractors []Extractor
+		db         *sql.DB
+	}{{
+		about: "pgdbtemplate-pq",
+		conn:  &pgdbtemplatepq.DatabaseConnection{DB: pqDB},
+		db:    pqDB,
+	}, {
+		about: "Connection",
+		conn:  &sqlDBConnection{db: customDB},
+		db:    customDB,
+	}, {
+		about: "extractors first",
+		conn:  &sqlDBConnection{db: customDB},
+		extractors: []Extractor{
+			func(pgdbtemplate.DatabaseConnection) (*sql.DB, bool) { return nil, false },
+			func(pgdbtemplate.DatabaseConnection) (*sql.DB, bool) { return extractedDB, true },
+		},
+		db: extractedDB,
+	}} {
+		db, opened, err := Extract(test.conn, test.extractors...)
+		c.Assert(err, qt.IsNil, qt.Commentf(test.about))
+		c.Assert(opened, qt.IsFalse, qt.Commentf(test.about))
+		c.Assert(db, qt.Equals, test.db, qt.Commentf(test.about))
+	}
+
+	_, _, err := Extract(&otherConnection{})
+	c.Assert(err, qt.ErrorMatches, "goose adapter requires .*, got \\*sqldb.otherConnection")
+}
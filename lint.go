@@ -0,0 +1,205 @@
+package pgdbtemplategoose
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+)
+
+// LintIssue is a problem found in a migration.
+type LintIssue struct {
+	// File is the migration file. It is empty for problems
+	// of Go migrations registered on the runner.
+	File string
+	// Line is the 1-based line of the problem, or zero
+	// if the problem concerns the whole file.
+	Line int
+	// Message describes the problem.
+	Message string
+}
+
+// String formats the issue as "file:line: message".
+func (i LintIssue) String() string {
+	switch {
+	case i.File == "":
+		return i.Message
+	case i.Line == 0:
+		return fmt.Sprintf("%s: %s", i.File, i.Message)
+	default:
+		return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+	}
+}
+
+// LintError is returned by RunMigrations with WithLint
+// when migrations have problems.
+type LintError struct {
+	Issues []LintIssue
+}
+
+func (e *LintError) Error() string {
+	lines := make([]string, 0, len(e.Issues)+1)
+	lines = append(lines, fmt.Sprintf("found %d migration problem(s):", len(e.Issues)))
+	for _, issue := range e.Issues {
+		lines = append(lines, "\t"+issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// nonTransactionalStatements cannot run inside a transaction,
+// so migrations containing them need "-- +goose NO TRANSACTION".
+var nonTransactionalStatements = []struct {
+	pattern *regexp.Regexp
+	name    string
+}{
+	{regexp.MustCompile(`(?is)^\s*CREATE\s+(UNIQUE\s+)?INDEX\s+CONCURRENTLY\b`), "CREATE INDEX CONCURRENTLY"},
+	{regexp.MustCompile(`(?is)^\s*DROP\s+INDEX\s+CONCURRENTLY\b`), "DROP INDEX CONCURRENTLY"},
+	{regexp.MustCompile(`(?is)^\s*REINDEX\b.*\bCONCURRENTLY\b`), "REINDEX CONCURRENTLY"},
+	{regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\b.*\bDETACH\s+PARTITION\b.*\bCONCURRENTLY\b`), "DETACH PARTITION CONCURRENTLY"},
+	{regexp.MustCompile(`(?is)^\s*VACUUM\b`), "VACUUM"},
+	{regexp.MustCompile(`(?is)^\s*(CREATE|DROP)\s+DATABASE\b`), "CREATE/DROP DATABASE"},
+	{regexp.MustCompile(`(?is)^\s*(CREATE|DROP)\s+TABLESPACE\b`), "CREATE/DROP TABLESPACE"},
+}
+
+// Lint checks migrations without running them and reports all problems found:
+//   - SQL files goose cannot parse, e.g. missing "-- +goose Up" annotations,
+//     unbalanced StatementBegin/StatementEnd annotations or statements
+//     without a terminating semicolon;
+//   - duplicate versions, among files and Go migrations registered on the runner;
+//   - statements that cannot run inside a transaction, such as
+//     CREATE INDEX CONCURRENTLY, in migrations without
+//     "-- +goose NO TRANSACTION".
+//
+// Issues are sorted by file and line. The error is only non-nil if the
+// migrations could not be read at all or the runner is misconfigured,
+// as checked before RunMigrations and DryRun too.
+//
+// Example:
+//
+//	issues, err := runner.Lint()
+//	if err != nil {
+//	    return err
+//	}
+//	for _, issue := range issues {
+//	    fmt.Println(issue)
+//	}
+func (r *MigrationRunner) Lint() ([]LintIssue, error) {
+	if err := r.preflight(); err != nil {
+		return nil, err
+	}
+	return r.lintIssues()
+}
+
+// lintIssues lints migrations of an already pre-flight checked runner.
+func (r *MigrationRunner) lintIssues() ([]LintIssue, error) {
+	sources, err := listSources(r.migrationsFs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect migrations: %w", err)
+	}
+
+	var issues []LintIssue
+	issues = append(issues, r.lintVersions(sources)...)
+	for _, source := range sources {
+		if source.Type != goose.TypeSQL {
+			continue
+		}
+		issues = append(issues, r.lintSQLFile(source.Path)...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return issues, nil
+}
+
+// lint runs Lint as a pre-flight check of RunMigrations.
+func (r *MigrationRunner) lint() error {
+	issues, err := r.lintIssues()
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		return &LintError{Issues: issues}
+	}
+	return nil
+}
+
+// lintVersions reports versions used by more than one migration.
+func (r *MigrationRunner) lintVersions(sources []goose.Source) []LintIssue {
+	first := make(map[int64]string)
+	var issues []LintIssue
+	for _, source := range sources {
+		if existing, ok := first[source.Version]; ok {
+			issues = append(issues, LintIssue{
+				File:    source.Path,
+				Message: fmt.Sprintf("duplicate migration version %d, also used by %s", source.Version, existing),
+			})
+			continue
+		}
+		first[source.Version] = source.Path
+	}
+
+	for _, m := range r.goMigrations {
+		if existing, ok := first[m.version]; ok {
+			issues = append(issues, LintIssue{
+				File:    existing,
+				Message: fmt.Sprintf("duplicate migration version %d, also used by a Go migration registered on the runner", m.version),
+			})
+		}
+	}
+	return issues
+}
+
+// lintSQLFile reports problems of a single SQL migration file.
+func (r *MigrationRunner) lintSQLFile(path string) []LintIssue {
+	f, err := r.migrationsFs.Open(path)
+	if err != nil {
+		return []LintIssue{{File: path, Message: err.Error()}}
+	}
+	defer f.Close()
+
+	parsed, err := parseSQLMigration(f)
+	if err != nil {
+		var parseErr *sqlParseError
+		if errors.As(err, &parseErr) {
+			return []LintIssue{{File: path, Line: parseErr.line, Message: parseErr.err.Error()}}
+		}
+		return []LintIssue{{File: path, Message: err.Error()}}
+	}
+
+	if !parsed.useTx {
+		return nil
+	}
+	var issues []LintIssue
+	for _, stmt := range append(append([]sqlStatement(nil), parsed.up...), parsed.down...) {
+		for _, nonTx := range nonTransactionalStatements {
+			if nonTx.pattern.MatchString(stripSQLComments(stmt.sql)) {
+				issues = append(issues, LintIssue{
+					File:    path,
+					Line:    stmt.line,
+					Message: fmt.Sprintf("%s cannot run inside a transaction: add '-- +goose NO TRANSACTION'", nonTx.name),
+				})
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// stripSQLComments removes "--" comment lines from a statement.
+func stripSQLComments(sql string) string {
+	lines := strings.Split(sql, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,104 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMigrationRunnerLint(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	c.Run("Clean migrations", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(fstest.MapFS{
+			"00001_create_users.sql": {Data: []byte("-- +goose Up\nCREATE TABLE users (id int);\n\n-- +goose Down\nDROP TABLE users;\n")},
+			"00002_index_users.sql": {Data: []byte(`-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY users_id_idx ON users (id);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY users_id_idx;
+`)},
+			"README.md": {Data: []byte("-- not a migration")},
+		})
+		issues, err := runner.Lint()
+		c.Assert(err, qt.IsNil)
+		c.Assert(issues, qt.HasLen, 0)
+	})
+
+	c.Run("All problems at once", func(c *qt.C) {
+		c.Parallel()
+
+		noopTx := func(context.Context, *sql.Tx) error { return nil }
+		runner := pgdbtemplategoose.NewMigrationRunner(fstest.MapFS{
+			"00001_no_up.sql": {Data: []byte("CREATE TABLE users (id int);\n")},
+			"00002_unbalanced.sql": {Data: []byte(`-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION f() RETURNS int AS $$ SELECT 1 $$ LANGUAGE sql;
+`)},
+			"00003_create_posts.sql":    {Data: []byte("-- +goose Up\nCREATE TABLE posts (id int);\n")},
+			"00003_create_comments.sql": {Data: []byte("-- +goose Up\nCREATE TABLE comments (id int);\n")},
+			"00004_index_posts.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE tags (id int);
+-- Speeds up lookups.
+CREATE UNIQUE INDEX CONCURRENTLY posts_id_idx
+    ON posts (id);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY posts_id_idx;
+DROP TABLE tags;
+`)},
+			"00005_missing_semicolon.sql": {Data: []byte("-- +goose Up\nCREATE TABLE a (id int)\n")},
+		}, pgdbtemplategoose.WithGoMigration(5, noopTx, nil))
+
+		issues, err := runner.Lint()
+		c.Assert(err, qt.IsNil)
+
+		var formatted []string
+		for _, issue := range issues {
+			formatted = append(formatted, issue.String())
+		}
+		c.Assert(formatted, qt.DeepEquals, []string{
+			"00001_no_up.sql:1: must start with '-- +goose Up' annotation",
+			"00002_unbalanced.sql:3: missing '-- +goose StatementEnd' annotation",
+			"00003_create_posts.sql: duplicate migration version 3, also used by 00003_create_comments.sql",
+			"00004_index_posts.sql:4: CREATE INDEX CONCURRENTLY cannot run inside a transaction: add '-- +goose NO TRANSACTION'",
+			"00004_index_posts.sql:8: DROP INDEX CONCURRENTLY cannot run inside a transaction: add '-- +goose NO TRANSACTION'",
+			"00005_missing_semicolon.sql: duplicate migration version 5, also used by a Go migration registered on the runner",
+			`00005_missing_semicolon.sql:2: unfinished SQL statement "CREATE TABLE a (id int)": missing semicolon?`,
+		})
+	})
+
+	c.Run("Pre-flight check", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(fstest.MapFS{
+			"00001_no_up.sql": {Data: []byte("CREATE TABLE users (id int);\n")},
+		}, pgdbtemplategoose.WithLint())
+
+		// The check fails before the connection is used.
+		err := runner.RunMigrations(context.Background(), &sqlDBConnection{})
+		var lintErr *pgdbtemplategoose.LintError
+		c.Assert(errors.As(err, &lintErr), qt.IsTrue)
+		c.Assert(lintErr.Issues, qt.HasLen, 1)
+		c.Assert(err, qt.ErrorMatches, "found 1 migration problem\\(s\\):\n\t00001_no_up.sql:1: must start with '-- \\+goose Up' annotation")
+	})
+
+	c.Run("Misconfigured runner", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(fstest.MapFS{
+			"00001_create_users.sql": {Data: []byte("-- +goose Up\nCREATE TABLE users (id int);\n")},
+		}, pgdbtemplategoose.WithTableSchema("Ops"))
+		_, err := runner.Lint()
+		c.Assert(err, qt.ErrorMatches, `invalid version table schema "Ops": must be a lower-case unquoted identifier`)
+	})
+}
@@ -0,0 +1,552 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// defaultLockStatsInterval is the sampling interval used by WithLockStats
+// when none is given.
+const defaultLockStatsInterval = 10 * time.Millisecond
+
+// MigrationStats describes locks and statements observed
+// while a migration was running, see WithLockStats.
+type MigrationStats struct {
+	// Locks lists distinct locks held or awaited by the migration,
+	// sorted by relation and mode.
+	Locks []LockStat
+	// Statements lists statements executed by the migration,
+	// the slowest first.
+	Statements []StatementStat
+	// Source is where statement timing comes from: "pg_stat_statements"
+	// if the extension is installed in the database, or "pg_stat_activity"
+	// if statements were sampled.
+	Source string
+	// Samples is the number of times pg_locks was sampled.
+	Samples int
+}
+
+// LockStat is a lock observed in pg_locks.
+type LockStat struct {
+	// LockType is the type of the locked object, e.g. "relation" or "advisory".
+	LockType string
+	// Relation is the locked relation, empty for other lock types.
+	Relation string
+	// Mode is the lock mode, e.g. "AccessExclusiveLock".
+	Mode string
+	// Granted reports whether the lock was granted in any sample.
+	// Locks only seen awaited mean the migration waited for another session.
+	Granted bool
+}
+
+// StatementStat is the timing of a statement.
+type StatementStat struct {
+	// Query is the statement text; pg_stat_statements normalizes constants.
+	Query string
+	// Calls is the number of times the statement was executed.
+	Calls int64
+	// Duration is the total execution time of the statement. Sampled
+	// durations are lower bounds, precise to the sampling interval.
+	Duration time.Duration
+}
+
+// lockStatsConfig configures lock statistics collection.
+type lockStatsConfig struct {
+	interval time.Duration
+}
+
+// lockStatsMarker tags sampler queries so that they are excluded
+// from pg_stat_statements results.
+const lockStatsMarker = "/* pgdbtemplate-goose lock stats */"
+
+const (
+	// sampleLocksQuery lists locks of other sessions busy in the database.
+	// Virtual and transaction ID locks are held by every transaction.
+	sampleLocksQuery = lockStatsMarker + `
+SELECT l.locktype, coalesce(l.relation::regclass::text, ''), l.mode, l.granted
+FROM pg_locks l
+JOIN pg_stat_activity a ON a.pid = l.pid
+WHERE a.datname = current_database()
+  AND a.pid <> pg_backend_pid()
+  AND a.state <> 'idle'
+  AND l.locktype NOT IN ('virtualxid', 'transactionid')`
+
+	// sampleStatementsQuery lists statements running in the database.
+	sampleStatementsQuery = lockStatsMarker + `
+SELECT query, coalesce(extract(epoch FROM clock_timestamp() - query_start), 0)::float8
+FROM pg_stat_activity
+WHERE datname = current_database()
+  AND pid <> pg_backend_pid()
+  AND state = 'active'`
+
+	// statementTimeColumnQuery finds the total time column of
+	// pg_stat_statements, renamed in PostgreSQL 13, if the extension
+	// is installed in the database.
+	statementTimeColumnQuery = lockStatsMarker + `
+SELECT attname FROM pg_attribute
+WHERE attrelid = to_regclass('pg_stat_statements')
+  AND attname IN ('total_exec_time', 'total_time')
+  AND NOT attisdropped`
+
+	// statementCountersQuery lists pg_stat_statements counters of the
+	// database; %s is the total time column.
+	statementCountersQuery = lockStatsMarker + `
+SELECT queryid, query, calls, %s
+FROM pg_stat_statements
+WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+  AND queryid IS NOT NULL
+  AND query NOT LIKE '%%pgdbtemplate-goose lock stats%%'`
+)
+
+// statementCounter is a pg_stat_statements entry.
+type statementCounter struct {
+	query string
+	calls int64
+	time  float64 // milliseconds
+}
+
+// lockSampler observes migrations from a dedicated connection.
+type lockSampler struct {
+	conn     *sql.Conn
+	interval time.Duration
+	// timeColumn is the pg_stat_statements total time column,
+	// empty if the extension is not installed.
+	timeColumn string
+}
+
+// newLockSampler reserves a connection for sampling and detects
+// pg_stat_statements.
+func (r *MigrationRunner) newLockSampler(ctx context.Context, db *sql.DB) (*lockSampler, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve connection for lock statistics: %w", err)
+	}
+	sampler := &lockSampler{conn: conn, interval: r.lockStats.interval}
+	if sampler.interval <= 0 {
+		sampler.interval = defaultLockStatsInterval
+	}
+
+	rows, err := conn.QueryContext(ctx, statementTimeColumnQuery)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to detect pg_stat_statements: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to detect pg_stat_statements: %w", err)
+		}
+		// Prefer the PostgreSQL 13+ column should both be listed.
+		if sampler.timeColumn == "" || column == "total_exec_time" {
+			sampler.timeColumn = column
+		}
+	}
+	if err := rows.Err(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to detect pg_stat_statements: %w", err)
+	}
+	return sampler, nil
+}
+
+// close releases the sampling connection.
+func (s *lockSampler) close() error {
+	return s.conn.Close()
+}
+
+// lockSampling samples pg_locks, and pg_stat_activity unless
+// pg_stat_statements is available, from the start until stop is called.
+// Samples are attributed to windows closed by cut, one per migration.
+type lockSampling struct {
+	sampler *lockSampler
+
+	// mu guards the sampling connection and the fields below.
+	mu        sync.Mutex
+	collector *statsCollector
+	// counters are pg_stat_statements counters at the start of the window.
+	counters map[int64]statementCounter
+	err      error
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// start starts sampling in the background.
+func (s *lockSampler) start(ctx context.Context) (*lockSampling, error) {
+	sampling := &lockSampling{sampler: s, collector: newStatsCollector()}
+	if s.timeColumn != "" {
+		counters, err := s.statementCounters(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sampling.counters = counters
+	}
+
+	sampleCtx, cancel := context.WithCancel(ctx)
+	sampling.cancel = cancel
+	sampling.wg.Add(1)
+	go func() {
+		defer sampling.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			sampling.mu.Lock()
+			err := s.sample(sampleCtx, sampling.collector)
+			if err != nil && sampleCtx.Err() == nil && sampling.err == nil {
+				sampling.err = err
+			}
+			sampling.mu.Unlock()
+			if err != nil {
+				return
+			}
+			select {
+			case <-sampleCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return sampling, nil
+}
+
+// cut closes the current window and returns its statistics,
+// or nil if sampling failed.
+func (l *lockSampling) cut(ctx context.Context) *MigrationStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := l.collector.stats()
+	l.collector = newStatsCollector()
+	if l.sampler.timeColumn == "" {
+		stats.Source = "pg_stat_activity"
+		return stats
+	}
+
+	counters, err := l.sampler.statementCounters(ctx)
+	if err != nil {
+		if l.err == nil {
+			l.err = err
+		}
+		return nil
+	}
+	stats.Source = "pg_stat_statements"
+	stats.Statements = diffStatementCounters(l.counters, counters)
+	l.counters = counters
+	return stats
+}
+
+// stop stops sampling and returns the first sampling error.
+func (l *lockSampling) stop() error {
+	l.cancel()
+	l.wg.Wait()
+	return l.err
+}
+
+// measure runs the migration while sampling. The returned error is
+// a sampling error; the migration error is up to run to record.
+func (s *lockSampler) measure(ctx context.Context, run func()) (*MigrationStats, error) {
+	sampling, err := s.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	run()
+	stats := sampling.cut(ctx)
+	if err := sampling.stop(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// sample records locks and, without pg_stat_statements,
+// running statements once.
+func (s *lockSampler) sample(ctx context.Context, collector *statsCollector) error {
+	rows, err := s.conn.QueryContext(ctx, sampleLocksQuery)
+	if err != nil {
+		return fmt.Errorf("failed to sample pg_locks: %w", err)
+	}
+	defer rows.Close()
+	var locks []LockStat
+	for rows.Next() {
+		var lock LockStat
+		if err := rows.Scan(&lock.LockType, &lock.Relation, &lock.Mode, &lock.Granted); err != nil {
+			return fmt.Errorf("failed to sample pg_locks: %w", err)
+		}
+		locks = append(locks, lock)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to sample pg_locks: %w", err)
+	}
+	collector.addLocks(locks)
+
+	if s.timeColumn != "" {
+		return nil
+	}
+	statementRows, err := s.conn.QueryContext(ctx, sampleStatementsQuery)
+	if err != nil {
+		return fmt.Errorf("failed to sample pg_stat_activity: %w", err)
+	}
+	defer statementRows.Close()
+	for statementRows.Next() {
+		var query string
+		var seconds float64
+		if err := statementRows.Scan(&query, &seconds); err != nil {
+			return fmt.Errorf("failed to sample pg_stat_activity: %w", err)
+		}
+		collector.addStatement(query, time.Duration(seconds*float64(time.Second)))
+	}
+	if err := statementRows.Err(); err != nil {
+		return fmt.Errorf("failed to sample pg_stat_activity: %w", err)
+	}
+	return nil
+}
+
+// statementCounters reads pg_stat_statements counters of the database.
+func (s *lockSampler) statementCounters(ctx context.Context) (map[int64]statementCounter, error) {
+	rows, err := s.conn.QueryContext(ctx, fmt.Sprintf(statementCountersQuery, s.timeColumn))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pg_stat_statements: %w", err)
+	}
+	defer rows.Close()
+	counters := make(map[int64]statementCounter)
+	for rows.Next() {
+		var id int64
+		var counter statementCounter
+		if err := rows.Scan(&id, &counter.query, &counter.calls, &counter.time); err != nil {
+			return nil, fmt.Errorf("failed to read pg_stat_statements: %w", err)
+		}
+		// The same statement may be listed for several users.
+		if existing, ok := counters[id]; ok {
+			counter.calls += existing.calls
+			counter.time += existing.time
+		}
+		counters[id] = counter
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pg_stat_statements: %w", err)
+	}
+	return counters, nil
+}
+
+// diffStatementCounters returns statements executed between two readings
+// of pg_stat_statements, the slowest first.
+func diffStatementCounters(before, after map[int64]statementCounter) []StatementStat {
+	var statements []StatementStat
+	for id, counter := range after {
+		calls := counter.calls - before[id].calls
+		if calls <= 0 {
+			continue
+		}
+		elapsed := counter.time - before[id].time
+		statements = append(statements, StatementStat{
+			Query:    counter.query,
+			Calls:    calls,
+			Duration: time.Duration(elapsed * float64(time.Millisecond)),
+		})
+	}
+	sortStatements(statements)
+	return statements
+}
+
+// sortStatements sorts statements by duration, the slowest first.
+func sortStatements(statements []StatementStat) {
+	sort.Slice(statements, func(i, j int) bool {
+		if statements[i].Duration != statements[j].Duration {
+			return statements[i].Duration > statements[j].Duration
+		}
+		return statements[i].Query < statements[j].Query
+	})
+}
+
+// lockKey identifies a lock regardless of whether it was granted.
+type lockKey struct {
+	lockType, relation, mode string
+}
+
+// statsCollector merges samples into MigrationStats.
+type statsCollector struct {
+	samples    int
+	locks      map[lockKey]bool
+	statements map[string]time.Duration
+}
+
+func newStatsCollector() *statsCollector {
+	return &statsCollector{
+		locks:      make(map[lockKey]bool),
+		statements: make(map[string]time.Duration),
+	}
+}
+
+// addLocks merges a pg_locks sample.
+func (c *statsCollector) addLocks(locks []LockStat) {
+	c.samples++
+	for _, lock := range locks {
+		key := lockKey{lock.LockType, lock.Relation, lock.Mode}
+		c.locks[key] = c.locks[key] || lock.Granted
+	}
+}
+
+// addStatement merges a statement seen running for the given time.
+// Statements are told apart by their text only.
+func (c *statsCollector) addStatement(query string, runningFor time.Duration) {
+	if runningFor > c.statements[query] {
+		c.statements[query] = runningFor
+	}
+}
+
+// stats returns the collected statistics.
+func (c *statsCollector) stats() *MigrationStats {
+	stats := &MigrationStats{Samples: c.samples}
+	for key, granted := range c.locks {
+		stats.Locks = append(stats.Locks, LockStat{
+			LockType: key.lockType,
+			Relation: key.relation,
+			Mode:     key.mode,
+			Granted:  granted,
+		})
+	}
+	sort.Slice(stats.Locks, func(i, j int) bool {
+		a, b := stats.Locks[i], stats.Locks[j]
+		if a.Relation != b.Relation {
+			return a.Relation < b.Relation
+		}
+		if a.LockType != b.LockType {
+			return a.LockType < b.LockType
+		}
+		return a.Mode < b.Mode
+	})
+	for query, duration := range c.statements {
+		stats.Statements = append(stats.Statements, StatementStat{Query: query, Calls: 1, Duration: duration})
+	}
+	sortStatements(stats.Statements)
+	return stats
+}
+
+// migrationApplier applies migrations one by one, collecting their
+// results and, with WithLockStats, their statistics.
+type migrationApplier struct {
+	provider *goose.Provider
+	// sampler is nil unless lock statistics are enabled.
+	sampler *lockSampler
+	report  *RunReport
+	results []*goose.MigrationResult
+}
+
+// newMigrationApplier creates an applier, reserving a sampling connection
+// if lock statistics are enabled. close must be called when done.
+func (r *MigrationRunner) newMigrationApplier(ctx context.Context, db *sql.DB, provider *goose.Provider, report *RunReport) (*migrationApplier, error) {
+	sampler, err := r.lockSamplerFor(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	return &migrationApplier{provider: provider, sampler: sampler, report: report}, nil
+}
+
+// lockSamplerFor returns a sampler for db, or nil if lock statistics
+// are disabled or db cannot spare a sampling connection.
+func (r *MigrationRunner) lockSamplerFor(ctx context.Context, db *sql.DB) (*lockSampler, error) {
+	if r.lockStats == nil {
+		return nil, nil
+	}
+	if !canSample(db) {
+		// goose holds one connection for the whole run, so waiting for
+		// a second one from a single-connection pool would never end.
+		if r.logger != nil {
+			r.logger.LogAttrs(ctx, slog.LevelWarn, "lock statistics skipped: the connection pool allows a single connection",
+				slog.Int("max_open_connections", db.Stats().MaxOpenConnections),
+			)
+		}
+		return nil, nil
+	}
+	return r.newLockSampler(ctx, db)
+}
+
+// canSample reports whether db can hand out a sampling connection
+// while goose holds another one.
+func canSample(db *sql.DB) bool {
+	limit := db.Stats().MaxOpenConnections
+	return limit == 0 || limit > 1
+}
+
+// apply runs a single migration and records its result,
+// including the failed one.
+func (a *migrationApplier) apply(ctx context.Context, version int64, up bool) error {
+	var result *goose.MigrationResult
+	var err error
+	run := func() {
+		result, err = a.provider.ApplyVersion(ctx, version, up)
+		var partialErr *goose.PartialError
+		if err != nil {
+			result = nil
+			if errors.As(err, &partialErr) {
+				result = partialErr.Failed
+			}
+		}
+	}
+
+	var stats *MigrationStats
+	var sampleErr error
+	if a.sampler != nil {
+		stats, sampleErr = a.sampler.measure(ctx, run)
+	} else {
+		run()
+	}
+
+	if result != nil {
+		a.results = append(a.results, result)
+		if stats != nil {
+			if a.report.stats == nil {
+				a.report.stats = make(map[*goose.MigrationResult]*MigrationStats)
+			}
+			a.report.stats[result] = stats
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return sampleErr
+}
+
+// close releases the sampling connection, if any.
+func (a *migrationApplier) close() error {
+	if a.sampler == nil {
+		return nil
+	}
+	return a.sampler.close()
+}
+
+// upSampled runs migrations like up while sampling locks. goose reports
+// each migration as it completes, so samples taken until then are
+// attributed to it, and the rest to the failed migration, if any.
+func (r *MigrationRunner) upSampled(ctx context.Context, db *sql.DB, provider *goose.Provider, observer *runObserver) (results []*goose.MigrationResult, err error) {
+	sampler, err := r.lockSamplerFor(ctx, db)
+	if err != nil || sampler == nil {
+		if err != nil {
+			return nil, err
+		}
+		return r.upProvider(ctx, provider)
+	}
+	defer func() {
+		if closeErr := sampler.close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release lock statistics connection: %w", closeErr))
+		}
+	}()
+
+	sampling, err := sampler.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	observer.sampling = sampling
+	results, err = r.upProvider(ctx, provider)
+	observer.finish(results, err)
+	observer.sampling = nil
+	return results, errors.Join(err, sampling.stop())
+}
@@ -0,0 +1,75 @@
+package pgdbtemplategoose
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestStatsCollector(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	collector := newStatsCollector()
+	collector.addLocks([]LockStat{
+		{LockType: "relation", Relation: "users", Mode: "AccessExclusiveLock", Granted: false},
+		{LockType: "advisory", Mode: "ExclusiveLock", Granted: true},
+	})
+	collector.addLocks([]LockStat{
+		{LockType: "relation", Relation: "users", Mode: "AccessExclusiveLock", Granted: true},
+		{LockType: "relation", Relation: "posts", Mode: "ShareLock", Granted: false},
+	})
+	collector.addStatement("ALTER TABLE users ADD COLUMN name text", 10*time.Millisecond)
+	collector.addStatement("ALTER TABLE users ADD COLUMN name text", 30*time.Millisecond)
+	collector.addStatement("CREATE INDEX ON posts (id)", 20*time.Millisecond)
+
+	c.Assert(collector.stats(), qt.DeepEquals, &MigrationStats{
+		Locks: []LockStat{
+			{LockType: "advisory", Mode: "ExclusiveLock", Granted: true},
+			{LockType: "relation", Relation: "posts", Mode: "ShareLock", Granted: false},
+			{LockType: "relation", Relation: "users", Mode: "AccessExclusiveLock", Granted: true},
+		},
+		Statements: []StatementStat{
+			{Query: "ALTER TABLE users ADD COLUMN name text", Calls: 1, Duration: 30 * time.Millisecond},
+			{Query: "CREATE INDEX ON posts (id)", Calls: 1, Duration: 20 * time.Millisecond},
+		},
+		Samples: 2,
+	})
+}
+
+func TestDiffStatementCounters(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	before := map[int64]statementCounter{
+		1: {query: "SELECT $1", calls: 3, time: 1.5},
+		2: {query: "UPDATE users SET name = $1", calls: 1, time: 2},
+	}
+	after := map[int64]statementCounter{
+		1: {query: "SELECT $1", calls: 3, time: 1.5},
+		2: {query: "UPDATE users SET name = $1", calls: 3, time: 7},
+		3: {query: "ALTER TABLE users ADD COLUMN age int", calls: 1, time: 12.25},
+	}
+	c.Assert(diffStatementCounters(before, after), qt.DeepEquals, []StatementStat{
+		{Query: "ALTER TABLE users ADD COLUMN age int", Calls: 1, Duration: 12250 * time.Microsecond},
+		{Query: "UPDATE users SET name = $1", Calls: 2, Duration: 5 * time.Millisecond},
+	})
+}
+
+func TestCanSample(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	// Opening does not connect, so no database is needed.
+	db, err := sql.Open("postgres", "host=localhost")
+	c.Assert(err, qt.IsNil)
+	defer db.Close()
+
+	c.Assert(canSample(db), qt.IsTrue)
+	db.SetMaxOpenConns(1)
+	c.Assert(canSample(db), qt.IsFalse)
+	db.SetMaxOpenConns(2)
+	c.Assert(canSample(db), qt.IsTrue)
+}
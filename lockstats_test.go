@@ -0,0 +1,226 @@
+package pgdbtemplategoose_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+	"github.com/pressly/goose/v3"
+)
+
+func TestMigrationRunnerLockStats(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	runner := pgdbtemplategoose.NewMigrationRunner(
+		writeMigrations(c, map[string]string{
+			"00001_create_items.sql": `-- +goose Up
+CREATE TABLE goose_lockstats_items (id SERIAL PRIMARY KEY);
+`,
+			// Keep the lock long enough to be sampled.
+			"00002_add_name.sql": `-- +goose Up
+ALTER TABLE goose_lockstats_items ADD COLUMN name TEXT;
+SELECT pg_sleep(0.2);
+`,
+		}),
+		pgdbtemplategoose.WithLockStats(0),
+	)
+
+	provider := pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+	tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+		ConnectionProvider: provider,
+		MigrationRunner:    runner,
+	})
+	c.Assert(err, qt.IsNil)
+	defer tm.Cleanup(ctx)
+
+	err = tm.Initialize(ctx)
+	c.Assert(err, qt.IsNil)
+
+	migrations := runner.LastReport().Migrations
+	c.Assert(migrations, qt.HasLen, 2)
+	for _, migration := range migrations {
+		c.Assert(migration.Stats, qt.IsNotNil)
+		c.Assert(migration.Stats.Samples > 0, qt.IsTrue)
+	}
+
+	stats := migrations[1].Stats
+	c.Assert(stats.Locks, qt.Contains, pgdbtemplategoose.LockStat{
+		LockType: "relation",
+		Relation: "goose_lockstats_items",
+		Mode:     "AccessExclusiveLock",
+		Granted:  true,
+	})
+
+	var sleepSeen bool
+	for _, statement := range stats.Statements {
+		sleepSeen = sleepSeen || strings.Contains(statement.Query, "pg_sleep")
+	}
+	c.Assert(sleepSeen, qt.IsTrue, qt.Commentf("statements from %s: %v", stats.Source, stats.Statements))
+}
+
+func TestMigrationRunnerLockStatsSingleConnection(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	// A deadlocked run would otherwise hang until the test timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+		ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+		MigrationRunner:    &pgdbtemplate.NoOpMigrationRunner{},
+	})
+	c.Assert(err, qt.IsNil)
+	defer tm.Cleanup(ctx)
+	c.Assert(tm.Initialize(ctx), qt.IsNil)
+
+	testDB, dbName, err := tm.CreateTestDatabase(ctx)
+	c.Assert(err, qt.IsNil)
+	testDB.Close()
+
+	db, err := sql.Open("postgres", testConnectionStringFunc(dbName))
+	c.Assert(err, qt.IsNil)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	var buf bytes.Buffer
+	runner := pgdbtemplategoose.NewMigrationRunner(
+		writeMigrations(c, map[string]string{
+			"00001_create_items.sql": `-- +goose Up
+CREATE TABLE goose_lockstats_single (id SERIAL PRIMARY KEY);
+`,
+		}),
+		pgdbtemplategoose.WithLockStats(0),
+		pgdbtemplategoose.WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+	)
+	err = runner.RunMigrations(ctx, &sqlDBConnection{db: db})
+	c.Assert(err, qt.IsNil)
+
+	migrations := runner.LastReport().Migrations
+	c.Assert(migrations, qt.HasLen, 1)
+	c.Assert(migrations[0].Stats, qt.IsNil)
+
+	var warned bool
+	for _, record := range logRecords(c, &buf) {
+		warned = warned || (record["level"] == "WARN" && strings.HasPrefix(record["msg"].(string), "lock statistics skipped"))
+	}
+	c.Assert(warned, qt.IsTrue)
+}
+
+func TestMigrationRunnerLockStatsGooseOptions(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	// createTestDatabase returns the name of an empty test database.
+	createTestDatabase := func(c *qt.C) string {
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+			MigrationRunner:    &pgdbtemplate.NoOpMigrationRunner{},
+		})
+		c.Assert(err, qt.IsNil)
+		c.Assert(tm.Initialize(ctx), qt.IsNil)
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { tm.DropTestDatabase(ctx, dbName) })
+		testDB.Close()
+		return dbName
+	}
+
+	// connect opens a connection pool of its own to the database.
+	connect := func(c *qt.C, dbName string) pgdbtemplate.DatabaseConnection {
+		db, err := sql.Open("postgres", testConnectionStringFunc(dbName))
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { db.Close() })
+		return &sqlDBConnection{db: db}
+	}
+
+	c.Run("Session lock", func(c *qt.C) {
+		c.Parallel()
+
+		dbName := createTestDatabase(c)
+		migrationsFs := writeMigrations(c, map[string]string{
+			"00001_create_jobs.sql": `-- +goose Up
+CREATE TABLE goose_lockstats_jobs (id SERIAL PRIMARY KEY);
+SELECT pg_sleep(0.5);
+`,
+			"00002_add_name.sql": `-- +goose Up
+ALTER TABLE goose_lockstats_jobs ADD COLUMN name TEXT;
+`,
+		})
+
+		runners := make([]*pgdbtemplategoose.MigrationRunner, 2)
+		errs := make([]error, len(runners))
+		var wg sync.WaitGroup
+		for i := range runners {
+			i := i
+			runners[i] = pgdbtemplategoose.NewMigrationRunner(
+				migrationsFs,
+				pgdbtemplategoose.WithLockStats(0),
+				pgdbtemplategoose.WithSessionLock(time.Minute, 100*time.Millisecond),
+			)
+			conn := connect(c, dbName)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = runners[i].RunMigrations(ctx, conn)
+			}()
+		}
+		wg.Wait()
+		c.Assert(errs, qt.DeepEquals, []error{nil, nil})
+
+		// The runner waiting for the lock finds nothing left to apply.
+		var applied int
+		for _, runner := range runners {
+			for _, migration := range runner.LastReport().Migrations {
+				c.Assert(migration.Stats, qt.IsNotNil)
+				applied++
+			}
+		}
+		c.Assert(applied, qt.Equals, 2)
+	})
+
+	c.Run("Allow out of order", func(c *qt.C) {
+		c.Parallel()
+
+		dbName := createTestDatabase(c)
+		conn := connect(c, dbName)
+		files := map[string]string{
+			"00001_create_jobs.sql": `-- +goose Up
+CREATE TABLE goose_lockstats_ooo (id SERIAL PRIMARY KEY);
+`,
+			"00003_add_name.sql": `-- +goose Up
+ALTER TABLE goose_lockstats_ooo ADD COLUMN name TEXT;
+`,
+		}
+		runner := pgdbtemplategoose.NewMigrationRunner(writeMigrations(c, files))
+		c.Assert(runner.RunMigrations(ctx, conn), qt.IsNil)
+
+		files["00002_add_note.sql"] = `-- +goose Up
+ALTER TABLE goose_lockstats_ooo ADD COLUMN note TEXT;
+`
+		runner = pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, files),
+			pgdbtemplategoose.WithLockStats(0),
+			pgdbtemplategoose.WithGooseOptions(goose.WithAllowOutofOrder(true)),
+		)
+		c.Assert(runner.RunMigrations(ctx, conn), qt.IsNil)
+
+		migrations := runner.LastReport().Migrations
+		c.Assert(migrations, qt.HasLen, 1)
+		c.Assert(migrations[0].Version, qt.Equals, int64(2))
+		c.Assert(migrations[0].Stats, qt.IsNotNil)
+	})
+}
@@ -0,0 +1,72 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/pressly/goose/v3"
+)
+
+// logRunStart logs the start of a RunMigrations call.
+func (r *MigrationRunner) logRunStart(ctx context.Context) {
+	if r.logger == nil {
+		return
+	}
+	r.logger.LogAttrs(ctx, slog.LevelInfo, "migration run started",
+		slog.String("dialect", string(r.dialect)),
+		slog.Int64("target_version", r.targetVersion),
+	)
+}
+
+// logMigration logs a migration that has just completed.
+func (r *MigrationRunner) logMigration(ctx context.Context, result *goose.MigrationResult, schema string) {
+	if r.logger == nil {
+		return
+	}
+
+	level := slog.LevelInfo
+	msg := "migration applied"
+	if result.Direction == "down" {
+		msg = "migration rolled back"
+	}
+	attrs := []slog.Attr{
+		slog.Int64("version", result.Source.Version),
+		slog.String("file", result.Source.Path),
+		slog.String("source", r.migrationSourceName(result.Source.Path)),
+	}
+	if schema != "" {
+		attrs = append(attrs, slog.String("schema", schema))
+	}
+	attrs = append(attrs,
+		slog.String("direction", result.Direction),
+		slog.Duration("duration", result.Duration),
+		slog.Bool("empty", result.Empty),
+		slog.String("result", "ok"),
+	)
+	if result.Error != nil {
+		level = slog.LevelError
+		msg = "migration failed"
+		attrs[len(attrs)-1] = slog.String("result", "failed")
+		attrs = append(attrs, slog.Any("error", result.Error))
+	}
+	r.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// logRunFinish logs the end of a RunMigrations call.
+func (r *MigrationRunner) logRunFinish(ctx context.Context, report *RunReport) {
+	if r.logger == nil {
+		return
+	}
+
+	level := slog.LevelInfo
+	attrs := []slog.Attr{
+		slog.Int("migrations", len(report.Migrations)),
+		slog.Duration("duration", report.Duration),
+		slog.Bool("reused", report.Reused),
+	}
+	if report.Error != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.Any("error", report.Error))
+	}
+	r.logger.LogAttrs(ctx, level, "migration run finished", attrs...)
+}
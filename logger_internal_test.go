@@ -0,0 +1,51 @@
+package pgdbtemplategoose
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/pressly/goose/v3"
+)
+
+func TestLogMigrationMessages(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	var buf bytes.Buffer
+	r := &MigrationRunner{logger: slog.New(slog.NewTextHandler(&buf, nil))}
+	observer := r.newRunObserver(context.Background(), &RunReport{}, "tenant_a")
+
+	// Each migration is logged as soon as goose reports it, once.
+	applied := &goose.MigrationResult{Source: &goose.Source{Version: 1}, Direction: "up"}
+	observer.Printf("%s", applied)
+	c.Assert(buf.String(), qt.Contains, `level=INFO msg="migration applied" version=1`)
+	observer.Printf("%s", &goose.MigrationResult{Source: &goose.Source{Version: 1}, Direction: "down"})
+	observer.finish(nil, &goose.PartialError{
+		Applied: []*goose.MigrationResult{applied},
+		Failed:  &goose.MigrationResult{Source: &goose.Source{Version: 2}, Direction: "up", Error: errors.New("boom")},
+	})
+	r.logRunFinish(context.Background(), &RunReport{})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	c.Assert(lines, qt.HasLen, 4)
+	c.Assert(lines[0], qt.Contains, `schema=tenant_a direction=up`)
+	c.Assert(lines[1], qt.Contains, `level=INFO msg="migration rolled back"`)
+	c.Assert(lines[2], qt.Contains, `level=ERROR msg="migration failed"`)
+	c.Assert(lines[3], qt.Contains, `msg="migration run finished"`)
+}
+
+func TestRunObserverFatalf(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	var buf bytes.Buffer
+	r := &MigrationRunner{logger: slog.New(slog.NewTextHandler(&buf, nil))}
+	logger := r.newRunObserver(context.Background(), nil, "")
+	c.Assert(func() { logger.Fatalf("goose: %s\n", "broken") }, qt.PanicMatches, "goose: broken")
+	c.Assert(buf.String(), qt.Contains, `level=ERROR msg="goose: broken" component=goose`)
+}
@@ -0,0 +1,122 @@
+package pgdbtemplategoose_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+// logRecords decodes JSON log records.
+func logRecords(c *qt.C, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var record map[string]any
+		c.Assert(decoder.Decode(&record), qt.IsNil)
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestMigrationRunnerLogger(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	c.Run("Migrations", func(c *qt.C) {
+		c.Parallel()
+
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, map[string]string{
+				"00001_create_events.sql": `-- +goose Up
+CREATE TABLE goose_logger_events (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE goose_logger_events;
+`,
+				"00002_broken.sql": `-- +goose Up
+SELECT * FROM goose_logger_missing;
+
+-- +goose Down
+SELECT 1;
+`,
+			}),
+			pgdbtemplategoose.WithLogger(logger),
+		)
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+		defer tm.Cleanup(ctx)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.ErrorMatches, ".*goose_logger_missing.*")
+
+		var messages []string
+		var goose int
+		for _, record := range logRecords(c, &buf) {
+			if record["component"] == "goose" {
+				c.Assert(record["level"], qt.Equals, "DEBUG")
+				goose++
+				continue
+			}
+			messages = append(messages, record["msg"].(string))
+
+			if record["version"] == nil {
+				continue
+			}
+			switch record["version"] {
+			case float64(1):
+				c.Assert(record["level"], qt.Equals, "INFO")
+				c.Assert(record["file"], qt.Equals, "00001_create_events.sql")
+				c.Assert(record["direction"], qt.Equals, "up")
+				c.Assert(record["result"], qt.Equals, "ok")
+			case float64(2):
+				c.Assert(record["level"], qt.Equals, "ERROR")
+				c.Assert(record["result"], qt.Equals, "failed")
+				c.Assert(record["error"], qt.Matches, ".*goose_logger_missing.*")
+			}
+		}
+		c.Assert(messages, qt.DeepEquals, []string{
+			"migration run started",
+			"migration applied",
+			"migration failed",
+			"migration run finished",
+		})
+		c.Assert(goose > 0, qt.IsTrue, qt.Commentf("goose output was not routed to the logger"))
+	})
+
+	c.Run("Failed run", func(c *qt.C) {
+		c.Parallel()
+
+		var buf bytes.Buffer
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, nil),
+			pgdbtemplategoose.WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+		)
+		c.Assert(runner.Close(), qt.IsNil)
+
+		err := runner.RunMigrations(ctx, &sqlDBConnection{})
+		c.Assert(err, qt.ErrorIs, pgdbtemplategoose.ErrRunnerClosed)
+
+		records := logRecords(c, &buf)
+		c.Assert(records, qt.HasLen, 2)
+		c.Assert(records[0]["msg"], qt.Equals, "migration run started")
+		c.Assert(records[0]["dialect"], qt.Equals, "postgres")
+		c.Assert(records[1]["msg"], qt.Equals, "migration run finished")
+		c.Assert(records[1]["level"], qt.Equals, "ERROR")
+		c.Assert(records[1]["error"], qt.Equals, "migration runner is closed")
+		c.Assert(records[1]["migrations"], qt.Equals, float64(0))
+	})
+}
@@ -3,23 +3,98 @@ package pgdbtemplategoose
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/andrei-polukhin/pgdbtemplate"
 	pgdbtemplatepgx "github.com/andrei-polukhin/pgdbtemplate-pgx"
-	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
-	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // MigrationRunner implements pgdbtemplate.MigrationRunner using goose.
 type MigrationRunner struct {
 	migrationsFs fs.FS
 	dialect      goose.Dialect
+	tableName    string
+	tableSchema  string
 	opts         []goose.ProviderOption
+
+	// migrationSources are merged with migrationsFs on creation.
+	migrationSources []MigrationSource
+
+	// targetVersion is the version to migrate the template to.
+	// Zero means the latest available version.
+	targetVersion int64
+
+	// goMigrations are Go migrations registered on this runner.
+	goMigrations []goMigration
+	// disableGlobalRegistry excludes Go migrations from goose's
+	// global registry even if no Go migrations are registered on the runner.
+	disableGlobalRegistry bool
+
+	// verifyRoundTrip rolls back and re-applies every migration
+	// while building the template.
+	verifyRoundTrip bool
+
+	// schemaSnapshot is the golden file the migrated schema is compared with.
+	schemaSnapshot *schemaSnapshotConfig
+
+	// seedsFs contains fixtures loaded after migrations.
+	seedsFs fs.FS
+
+	// sessionLock serializes migrations of the same database
+	// across processes, if set.
+	sessionLock *sessionLockConfig
+
+	// lintBeforeRun runs Lint as a pre-flight check.
+	lintBeforeRun bool
+
+	// schemas are the schemas migrations are applied into, one by one.
+	schemas []string
+
+	// lockStats enables per-migration lock statistics.
+	lockStats *lockStatsConfig
+
+	// policyRules inspect migration statements before runs.
+	policyRules []PolicyRule
+
+	// reusePolicy controls reuse of already migrated databases.
+	reusePolicy ReusePolicy
+
+	// pgxNative runs SQL migrations directly over pgx pools
+	// for pgdbtemplate-pgx connections.
+	pgxNative bool
+
+	// extractors are user-registered connection extractors,
+	// tried before the built-in ones.
+	extractors []ConnectionExtractor
+
+	reportCallback func(*RunReport)
+	// tracerProvider and meterProvider are OpenTelemetry providers
+	// instrumenting runs, if set.
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	telemetry      *telemetry
+	// logger receives structured run events and goose's output, if set.
+	logger *slog.Logger
+
+	mu         sync.Mutex
+	lastReport *RunReport
+	// openDBs are *sql.DB wrappers opened by in-flight runs.
+	openDBs map[*sql.DB]struct{}
+	closed  bool
 }
 
+// ErrRunnerClosed is returned by RunMigrations after the runner was closed.
+var ErrRunnerClosed = errors.New("migration runner is closed")
+
 // NewMigrationRunner creates a new goose-based migration runner.
 //
 // The migrationsFs parameter accepts any fs.FS implementation containing goose migration files.
@@ -43,55 +118,321 @@ func NewMigrationRunner(migrationsFs fs.FS, options ...Option) *MigrationRunner
 	runner := &MigrationRunner{
 		migrationsFs: migrationsFs,
 		dialect:      goose.DialectPostgres,
+		tableName:    goose.DefaultTablename,
 	}
 
 	for _, opt := range options {
 		opt(runner)
 	}
+	runner.mergeMigrationSources()
+	if runner.tracerProvider != nil || runner.meterProvider != nil {
+		runner.telemetry = newTelemetry(runner.tracerProvider, runner.meterProvider)
+	}
 	return runner
 }
 
 // RunMigrations implements pgdbtemplate.MigrationRunner.RunMigrations.
 //
-// It runs all pending goose migrations on the provided database connection.
+// It runs all pending goose migrations on the provided database connection,
+// stopping at the target version if one was set with WithTargetVersion.
 // Supports both pgdbtemplate-pq (database/sql) and pgdbtemplate-pgx (pgx/v5).
+//
+// A report of the run is available afterwards via LastReport
+// and is passed to the callback set with WithReportCallback.
 func (r *MigrationRunner) RunMigrations(ctx context.Context, conn pgdbtemplate.DatabaseConnection) error {
-	// Extract *sql.DB from connection.
-	// This assumes the connection is from pgdbtemplate-pq which embeds *sql.DB.
-	db, err := r.extractSQLDB(conn)
+	_, err := r.run(ctx, conn)
+	return err
+}
+
+// run runs migrations like RunMigrations and returns the report of the run.
+func (r *MigrationRunner) run(ctx context.Context, conn pgdbtemplate.DatabaseConnection) (*RunReport, error) {
+	ctx, span := r.telemetry.startRun(ctx, r)
+	r.logRunStart(ctx)
+	start := time.Now()
+	report := &RunReport{}
+	results, err := r.runMigrations(ctx, conn, report)
+
+	end := time.Now()
+	report.Migrations = newMigrationResults(results, err, report)
+	for i := range report.Migrations {
+		report.Migrations[i].Source = r.migrationSourceName(report.Migrations[i].Path)
+	}
+	report.Duration = end.Sub(start)
+	report.Error = err
+	r.recordReport(report)
+	r.logRunFinish(ctx, report)
+	r.telemetry.finishRun(ctx, span, report, end)
+	return report, err
+}
+
+// LastReport returns the report of the most recent RunMigrations call,
+// or nil if RunMigrations has not been called yet.
+func (r *MigrationRunner) LastReport() *RunReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastReport
+}
+
+// Close releases resources held by the runner.
+//
+// It closes *sql.DB wrappers still opened by in-flight RunMigrations calls
+// for pgdbtemplate-pgx connections; completed runs release theirs on their own.
+// RunMigrations returns ErrRunnerClosed once the runner has been closed.
+// Close is safe to call multiple times.
+func (r *MigrationRunner) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+
+	var errs error
+	for db := range r.openDBs {
+		if err := db.Close(); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	r.openDBs = nil
+	return errs
+}
+
+// isClosed reports whether Close has been called.
+func (r *MigrationRunner) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+// preflight validates the runner configuration and migration sources
+// before anything touches the database. RunMigrations, DryRun and Lint
+// share it, so that all of them reject the same misconfigurations.
+func (r *MigrationRunner) preflight() error {
+	if err := checkMigrationSources(r.migrationsFs); err != nil {
+		return fmt.Errorf("failed to collect migrations: %w", err)
+	}
+	if err := r.checkVersionTable(); err != nil {
+		return err
+	}
+	return r.checkSchemas()
+}
+
+// runMigrations runs pending migrations and returns their goose results.
+// Run details other than the results are recorded in report.
+func (r *MigrationRunner) runMigrations(ctx context.Context, conn pgdbtemplate.DatabaseConnection, report *RunReport) (_ []*goose.MigrationResult, err error) {
+	if r.isClosed() {
+		return nil, ErrRunnerClosed
+	}
+
+	if err := r.preflight(); err != nil {
+		return nil, err
+	}
+	if r.lintBeforeRun {
+		if err := r.lint(); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.enforcePolicy(ctx, report); err != nil {
+		return nil, err
+	}
+
+	// Bypass database/sql altogether in pgx-native mode.
+	if pgxConn, ok := conn.(*pgdbtemplatepgx.DatabaseConnection); ok && r.pgxNative {
+		if r.reusePolicy != ReuseDisabled {
+			return nil, errors.New("database reuse is not supported in pgx-native mode")
+		}
+		if r.verifyRoundTrip {
+			return nil, errors.New("round-trip verification is not supported in pgx-native mode")
+		}
+		if r.schemaSnapshot != nil {
+			return nil, errors.New("schema snapshots are not supported in pgx-native mode")
+		}
+		if r.seedsFs != nil {
+			return nil, errors.New("seeds are not supported in pgx-native mode")
+		}
+		if r.lockStats != nil {
+			return nil, errors.New("lock statistics are not supported in pgx-native mode")
+		}
+		if len(r.schemas) > 0 {
+			return nil, errors.New("per-schema runs are not supported in pgx-native mode")
+		}
+		return r.runPgxNative(ctx, pgxConn.Pool, report)
+	}
+
+	// Extract *sql.DB from connection as goose only speaks database/sql.
+	db, release, err := r.extractSQLDB(conn)
 	if err != nil {
-		return fmt.Errorf("goose adapter requires database/sql connection: %w", err)
+		return nil, fmt.Errorf("goose adapter requires database/sql connection: %w", err)
+	}
+	// Release the database after the run. goose.Provider.Close only closes
+	// this database, so it must not be called for databases owned by the
+	// connection, while releasing ours also releases the provider.
+	defer func() {
+		if releaseErr := release(); releaseErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release database: %w", releaseErr))
+		}
+	}()
+
+	if len(r.schemas) > 0 {
+		return r.runSchemas(ctx, db, report)
+	}
+	if err := r.ensureTableSchema(ctx, db); err != nil {
+		return nil, err
 	}
+	return r.migrate(ctx, db, "", r.versionTable(), report)
+}
+
+// migrate runs pending migrations on db, recording versions in versionTable.
+// The schema is the one of per-schema runs, empty otherwise.
+func (r *MigrationRunner) migrate(ctx context.Context, db *sql.DB, schema, versionTable string, report *RunReport) (results []*goose.MigrationResult, err error) {
+	observer := r.newRunObserver(ctx, report, schema)
+	defer func() {
+		observer.finish(results, err)
+	}()
 
 	// Create goose provider with dialect.
-	provider, err := goose.NewProvider(r.dialect, db, r.migrationsFs, r.opts...)
+	opts, err := r.providerOptions(observer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goose provider: %w", err)
+	}
+	provider, err := r.newProvider(db, versionTable, opts)
 	if err != nil {
-		return fmt.Errorf("failed to create goose provider: %w", err)
+		return nil, fmt.Errorf("failed to create goose provider: %w", err)
+	}
+
+	if r.reusePolicy != ReuseDisabled {
+		return r.runWithReuse(ctx, db, provider, versionTable, observer)
 	}
 
-	// Run migrations up to the latest version.
-	_, err = provider.Up(ctx)
+	// Run migrations up to the target version, or the latest one by default.
+	results, err = r.up(ctx, db, provider, observer)
 	if err != nil {
-		return fmt.Errorf("failed to run goose migrations: %w", err)
+		return results, fmt.Errorf("failed to run goose migrations: %w", err)
 	}
 
-	return nil
+	if err := r.afterUp(ctx, db); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// afterUp runs the stages following successful migrations:
+// the schema snapshot check, then seeding.
+func (r *MigrationRunner) afterUp(ctx context.Context, db *sql.DB) error {
+	if err := r.checkSchemaSnapshot(ctx, db); err != nil {
+		return err
+	}
+	return r.loadSeeds(ctx, db)
+}
+
+// providerOptions returns goose provider options for a single run,
+// reporting migrations to the observer.
+func (r *MigrationRunner) providerOptions(observer *runObserver) ([]goose.ProviderOption, error) {
+	opts := make([]goose.ProviderOption, 0, len(r.opts)+5)
+	if len(r.goMigrations) > 0 {
+		// Build fresh migrations every run: goose mutates them
+		// while collecting sources, and runs may happen concurrently.
+		migrations := make([]*goose.Migration, 0, len(r.goMigrations))
+		for _, m := range r.goMigrations {
+			migrations = append(migrations, m.build())
+		}
+		opts = append(opts, goose.WithGoMigrations(migrations...))
+	}
+	// Go migrations registered on the runner are isolated from the global
+	// registry, so that parallel runners do not see each other's migrations.
+	if r.disableGlobalRegistry || len(r.goMigrations) > 0 {
+		opts = append(opts, goose.WithDisableGlobalRegistry(true))
+	}
+	// Verbose output is what reports migrations to the observer.
+	opts = append(opts, goose.WithLogger(observer), goose.WithVerbose(true))
+	if r.sessionLock != nil {
+		locker, err := r.sessionLock.sessionLocker()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, goose.WithSessionLocker(locker))
+	}
+	return append(opts, r.opts...), nil
+}
+
+// newProvider creates a goose provider for db recording versions in
+// versionTable. A store is only built for version tables other than goose's
+// default, so that WithGooseOptions may still provide a custom store otherwise.
+func (r *MigrationRunner) newProvider(db *sql.DB, versionTable string, opts []goose.ProviderOption) (*goose.Provider, error) {
+	if versionTable == goose.DefaultTablename {
+		return goose.NewProvider(r.dialect, db, r.migrationsFs, opts...)
+	}
+	store, err := database.NewStore(r.dialect, versionTable)
+	if err != nil {
+		return nil, err
+	}
+	// The dialect must be empty when a store is given.
+	return goose.NewProvider("", db, r.migrationsFs, append(opts, goose.WithStore(store))...)
+}
+
+// recordReport stores the report and passes it to the report callback.
+func (r *MigrationRunner) recordReport(report *RunReport) {
+	r.mu.Lock()
+	r.lastReport = report
+	r.mu.Unlock()
+
+	if r.reportCallback != nil {
+		r.reportCallback(report)
+	}
+}
+
+// up applies pending migrations up to the configured target version.
+func (r *MigrationRunner) up(ctx context.Context, db *sql.DB, provider *goose.Provider, observer *runObserver) ([]*goose.MigrationResult, error) {
+	if r.verifyRoundTrip {
+		return r.upVerified(ctx, db, provider, observer.report)
+	}
+	if r.lockStats != nil {
+		return r.upSampled(ctx, db, provider, observer)
+	}
+	return r.upProvider(ctx, provider)
 }
 
-// extractSQLDB attempts to extract *sql.DB from the connection.
-// Supports both pgdbtemplate-pq and pgdbtemplate-pgx.
-func (r *MigrationRunner) extractSQLDB(conn pgdbtemplate.DatabaseConnection) (*sql.DB, error) {
-	// Try pgdbtemplate-pq first (embeds *sql.DB).
-	if pqConn, ok := conn.(*pgdbtemplatepq.DatabaseConnection); ok {
-		return pqConn.DB, nil
+// upProvider runs goose.Provider.Up, or UpTo if a target version is set.
+func (r *MigrationRunner) upProvider(ctx context.Context, provider *goose.Provider) ([]*goose.MigrationResult, error) {
+	if r.targetVersion == 0 {
+		return provider.Up(ctx)
+	}
+	// Report a missing target version explicitly: goose.Provider.UpTo
+	// would otherwise silently stop at the closest lower version.
+	if err := r.checkTargetVersion(provider.ListSources()); err != nil {
+		return nil, err
 	}
+	return provider.UpTo(ctx, r.targetVersion)
+}
 
-	// Try pgdbtemplate-pgx (has Pool field).
-	if pgxConn, ok := conn.(*pgdbtemplatepgx.DatabaseConnection); ok {
-		// Wrap the pool with stdlib to get *sql.DB.
-		db := stdlib.OpenDBFromPool(pgxConn.Pool)
-		return db, nil
+// checkTargetVersion validates the configured target version against sources.
+// Zero, the latest version, is always valid.
+func (r *MigrationRunner) checkTargetVersion(sources []*goose.Source) error {
+	if r.targetVersion < 0 {
+		return fmt.Errorf("invalid target version %d: must be greater than 0", r.targetVersion)
 	}
+	if r.targetVersion > 0 && !hasVersion(sources, r.targetVersion) {
+		return fmt.Errorf("target version %d not found in migrations: %w", r.targetVersion, goose.ErrVersionNotFound)
+	}
+	return nil
+}
+
+// hasVersion reports whether sources contain the given version.
+func hasVersion(sources []*goose.Source, version int64) bool {
+	for _, source := range sources {
+		if source.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// goMigration is a Go migration registered on the runner.
+type goMigration struct {
+	version  int64
+	up, down goose.GoFunc
+}
 
-	return nil, fmt.Errorf("goose adapter requires pgdbtemplate-pq or pgdbtemplate-pgx connection, got %T", conn)
+// build creates a goose migration from the registered functions.
+func (m goMigration) build() *goose.Migration {
+	up, down := m.up, m.down
+	return goose.NewGoMigration(m.version, &up, &down)
 }
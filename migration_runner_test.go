@@ -2,6 +2,8 @@ package pgdbtemplategoose_test
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
@@ -19,6 +21,20 @@ func testConnectionStringFunc(dbName string) string {
 	return pgdbtemplate.ReplaceDatabaseInConnectionString(testConnectionString, dbName)
 }
 
+// writeMigrations writes the given migration files into a temporary
+// directory and returns it as fs.FS.
+func writeMigrations(c *qt.C, files map[string]string) fs.FS {
+	migrationsDir := filepath.Join(c.TempDir(), "migrations")
+	err := os.MkdirAll(migrationsDir, 0755)
+	c.Assert(err, qt.IsNil)
+
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(migrationsDir, name), []byte(content), 0644)
+		c.Assert(err, qt.IsNil)
+	}
+	return os.DirFS(migrationsDir)
+}
+
 func TestGooseMigrationRunner(t *testing.T) {
 	t.Parallel()
 	c := qt.New(t)
@@ -627,3 +643,117 @@ DROP TABLE goose_pgx_options_test;
 		c.Assert(tableName, qt.Equals, "goose_pgx_options_test")
 	})
 }
+
+func TestGooseMigrationRunnerTargetVersion(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	migrations := map[string]string{
+		"00001_create_accounts.sql": `-- +goose Up
+CREATE TABLE goose_target_accounts (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE goose_target_accounts;
+`,
+		"00002_add_email.sql": `-- +goose Up
+ALTER TABLE goose_target_accounts ADD COLUMN email TEXT;
+
+-- +goose Down
+ALTER TABLE goose_target_accounts DROP COLUMN email;
+`,
+		"00003_add_name.sql": `-- +goose Up
+ALTER TABLE goose_target_accounts ADD COLUMN name TEXT;
+
+-- +goose Down
+ALTER TABLE goose_target_accounts DROP COLUMN name;
+`,
+	}
+
+	c.Run("Migrate to target version", func(c *qt.C) {
+		c.Parallel()
+
+		migrationsFs := writeMigrations(c, migrations)
+		provider := pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			migrationsFs,
+			pgdbtemplategoose.WithTargetVersion(2),
+		)
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+		defer tm.Cleanup(ctx)
+
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		defer testDB.Close()
+		defer tm.DropTestDatabase(ctx, dbName)
+
+		pqConn := testDB.(*pgdbtemplatepq.DatabaseConnection)
+
+		// Version 2 is the latest applied one.
+		var version int64
+		err = pqConn.DB.QueryRowContext(ctx, "SELECT MAX(version_id) FROM goose_db_version").Scan(&version)
+		c.Assert(err, qt.IsNil)
+		c.Assert(version, qt.Equals, int64(2))
+
+		// Column from version 3 must not exist.
+		var count int
+		err = pqConn.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*)
+			FROM information_schema.columns
+			WHERE table_schema = 'public'
+			AND table_name = 'goose_target_accounts'
+			AND column_name IN ('email', 'name')
+		`).Scan(&count)
+		c.Assert(err, qt.IsNil)
+		c.Assert(count, qt.Equals, 1)
+	})
+
+	c.Run("Target version not found", func(c *qt.C) {
+		c.Parallel()
+
+		migrationsFs := writeMigrations(c, migrations)
+		provider := pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			migrationsFs,
+			pgdbtemplategoose.WithTargetVersion(42),
+		)
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.ErrorMatches, ".*target version 42 not found in migrations.*")
+		c.Assert(errors.Is(err, goose.ErrVersionNotFound), qt.IsTrue)
+	})
+
+	c.Run("Invalid target version", func(c *qt.C) {
+		c.Parallel()
+
+		migrationsFs := writeMigrations(c, migrations)
+		provider := pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			migrationsFs,
+			pgdbtemplategoose.WithTargetVersion(-1),
+		)
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.ErrorMatches, ".*invalid target version -1.*")
+	})
+}
@@ -0,0 +1,252 @@
+package pgdbtemplategoose
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// ErrSourceCollision is returned when migration sources set with
+// WithMigrationSources contain the same version or file name.
+var ErrSourceCollision = errors.New("migration sources collide")
+
+// MigrationSource is a named filesystem of goose migrations,
+// see WithMigrationSources.
+type MigrationSource struct {
+	// Name identifies the source in reports and errors, e.g. "billing".
+	Name string
+	// FS contains migration files at its root, like migrationsFs
+	// of NewMigrationRunner.
+	FS fs.FS
+}
+
+// label describes the source in errors.
+func (s MigrationSource) label() string {
+	if s.Name == "" {
+		return "migrationsFs"
+	}
+	return fmt.Sprintf("source %q", s.Name)
+}
+
+// sourcesFS merges files at the roots of several migration sources
+// into a single flat filesystem goose can read.
+//
+// The merge is computed once, on first use, and its error is returned by
+// every operation, so that collisions surface as errors of the operations
+// reading migrations.
+type sourcesFS struct {
+	sources []MigrationSource
+
+	once    sync.Once
+	merged  map[string]sourceFile
+	entries []fs.DirEntry
+	err     error
+}
+
+// sourceFile is a file of the merged filesystem.
+type sourceFile struct {
+	source int
+	entry  fs.DirEntry
+}
+
+// files returns files of all sources by name.
+func (f *sourcesFS) files() (map[string]sourceFile, error) {
+	f.once.Do(func() {
+		f.merged, f.err = f.merge()
+		if f.err != nil {
+			return
+		}
+		f.entries = make([]fs.DirEntry, 0, len(f.merged))
+		for _, file := range f.merged {
+			f.entries = append(f.entries, file.entry)
+		}
+		sort.Slice(f.entries, func(i, j int) bool {
+			return f.entries[i].Name() < f.entries[j].Name()
+		})
+	})
+	return f.merged, f.err
+}
+
+// merge reads the files of all sources. Migration files must have unique
+// versions and names across sources; for other files, which goose ignores,
+// the first source wins.
+func (f *sourcesFS) merge() (map[string]sourceFile, error) {
+	files := make(map[string]sourceFile)
+	versions := make(map[int64]string)
+	var collisions []error
+	for i, source := range f.sources {
+		entries, err := fs.ReadDir(source.FS, ".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", source.label(), err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			name := entry.Name()
+			version, isMigration := migrationVersion(name)
+			if existing, ok := files[name]; ok {
+				if isMigration {
+					collisions = append(collisions, fmt.Errorf("%w: %s is in both %s and %s",
+						ErrSourceCollision, name, f.sources[existing.source].label(), source.label()))
+				}
+				continue
+			}
+			if isMigration {
+				if existing, ok := versions[version]; ok {
+					collisions = append(collisions, fmt.Errorf("%w: version %d is used by %s in %s and by %s in %s",
+						ErrSourceCollision, version, existing, f.sources[files[existing].source].label(), name, source.label()))
+					continue
+				}
+				versions[version] = name
+			}
+			files[name] = sourceFile{source: i, entry: entry}
+		}
+	}
+	if len(collisions) > 0 {
+		return nil, errors.Join(collisions...)
+	}
+	return files, nil
+}
+
+// migrationVersion returns the version of a migration file name,
+// following the rules of collectSources.
+func migrationVersion(name string) (int64, bool) {
+	if ext := path.Ext(name); (ext != ".sql" && ext != ".go") || strings.HasSuffix(name, "_test.go") {
+		return 0, false
+	}
+	version, err := goose.NumericComponent(name)
+	return version, err == nil
+}
+
+// Open implements fs.FS. Only the root directory and files in it exist.
+func (f *sourcesFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		entries, err := f.ReadDir(".")
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &sourcesDir{entries: entries}, nil
+	}
+	if strings.Contains(name, "/") {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	files, err := f.files()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	file, ok := files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return f.sources[file.source].FS.Open(name)
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (f *sourcesFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if _, err := f.files(); err != nil {
+		return nil, err
+	}
+	// Callers may modify the returned slice.
+	return append([]fs.DirEntry(nil), f.entries...), nil
+}
+
+// sourceName returns the name of the source a file comes from.
+func (f *sourcesFS) sourceName(name string) string {
+	files, err := f.files()
+	if err != nil {
+		return ""
+	}
+	if file, ok := files[name]; ok {
+		return f.sources[file.source].Name
+	}
+	return ""
+}
+
+// sourcesDir is the root directory of sourcesFS.
+type sourcesDir struct {
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *sourcesDir) Stat() (fs.FileInfo, error) { return sourcesDirInfo{}, nil }
+func (d *sourcesDir) Close() error               { return nil }
+
+func (d *sourcesDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *sourcesDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
+
+// sourcesDirInfo describes the root directory of sourcesFS.
+type sourcesDirInfo struct{}
+
+func (sourcesDirInfo) Name() string       { return "." }
+func (sourcesDirInfo) Size() int64        { return 0 }
+func (sourcesDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o555 }
+func (sourcesDirInfo) ModTime() time.Time { return time.Time{} }
+func (sourcesDirInfo) IsDir() bool        { return true }
+func (sourcesDirInfo) Sys() any           { return nil }
+
+// mergeMigrationSources replaces migrationsFs with the merge of it and
+// sources set with WithMigrationSources, if any.
+func (r *MigrationRunner) mergeMigrationSources() {
+	if len(r.migrationSources) == 0 {
+		return
+	}
+	var sources []MigrationSource
+	if r.migrationsFs != nil {
+		sources = append(sources, MigrationSource{FS: r.migrationsFs})
+	}
+	r.migrationsFs = &sourcesFS{sources: append(sources, r.migrationSources...)}
+}
+
+// migrationSourceName returns the name of the source of a migration file,
+// or an empty string without WithMigrationSources.
+func (r *MigrationRunner) migrationSourceName(file string) string {
+	if merged, ok := r.migrationsFs.(*sourcesFS); ok && file != "" {
+		return merged.sourceName(file)
+	}
+	return ""
+}
+
+// checkMigrationSources reports collisions between sources merged into fsys.
+// Operations relying on fs.Glob must call it first, as fs.Glob ignores
+// errors reading directories and would see no migrations at all.
+func checkMigrationSources(fsys fs.FS) error {
+	if merged, ok := fsys.(*sourcesFS); ok {
+		_, err := merged.files()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package pgdbtemplategoose
+
+import (
+	"testing"
+	"testing/fstest"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSourcesFS(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	merged := &sourcesFS{sources: []MigrationSource{
+		{Name: "auth", FS: fstest.MapFS{
+			"00001_create_accounts.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+			"helpers.go":                {Data: []byte("package migrations")},
+			"nested/00005_ignored.sql":  {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+		}},
+		{Name: "billing", FS: fstest.MapFS{
+			"00002_create_invoices.sql": {Data: []byte("-- +goose Up\nSELECT 2;\n")},
+			"helpers.go":                {Data: []byte("package billing")},
+		}},
+	}}
+
+	err := fstest.TestFS(merged, "00001_create_accounts.sql", "00002_create_invoices.sql", "helpers.go")
+	c.Assert(err, qt.IsNil)
+
+	// Files other than migrations may repeat, the first source wins.
+	c.Assert(merged.sourceName("helpers.go"), qt.Equals, "auth")
+	c.Assert(merged.sourceName("00002_create_invoices.sql"), qt.Equals, "billing")
+	c.Assert(merged.sourceName("00003_missing.sql"), qt.Equals, "")
+}
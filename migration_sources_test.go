@@ -0,0 +1,113 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMigrationRunnerMigrationSources(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	auth := fstest.MapFS{
+		"00001_create_accounts.sql": {Data: []byte("-- +goose Up\nCREATE TABLE goose_sources_accounts (id SERIAL PRIMARY KEY);\n")},
+		"README.md":                 {Data: []byte("Auth migrations.")},
+	}
+	billing := fstest.MapFS{
+		"00002_create_invoices.sql": {Data: []byte("-- +goose Up\nCREATE TABLE goose_sources_invoices (id SERIAL PRIMARY KEY, account_id int REFERENCES goose_sources_accounts (id));\n")},
+		"README.md":                 {Data: []byte("Billing migrations.")},
+	}
+
+	c.Run("Merged run", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			fstest.MapFS{
+				"00003_create_products.sql": {Data: []byte("-- +goose Up\nCREATE TABLE goose_sources_products (id SERIAL PRIMARY KEY);\n")},
+			},
+			pgdbtemplategoose.WithMigrationSources(
+				pgdbtemplategoose.MigrationSource{Name: "billing", FS: billing},
+				pgdbtemplategoose.MigrationSource{Name: "auth", FS: auth},
+			),
+		)
+
+		provider := pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+		defer tm.Cleanup(ctx)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+
+		// Migrations run in version order regardless of their source.
+		var applied [][2]string
+		for _, m := range runner.LastReport().Migrations {
+			applied = append(applied, [2]string{m.Source, m.Path})
+		}
+		c.Assert(applied, qt.DeepEquals, [][2]string{
+			{"auth", "00001_create_accounts.sql"},
+			{"billing", "00002_create_invoices.sql"},
+			{"", "00003_create_products.sql"},
+		})
+	})
+
+	c.Run("Merged checks", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(nil, pgdbtemplategoose.WithMigrationSources(
+			pgdbtemplategoose.MigrationSource{Name: "auth", FS: auth},
+			pgdbtemplategoose.MigrationSource{Name: "billing", FS: billing},
+		))
+		issues, err := runner.Lint()
+		c.Assert(err, qt.IsNil)
+		c.Assert(issues, qt.HasLen, 0)
+
+		// Moving a migration to another source does not change the schema.
+		merged, err := runner.Fingerprint()
+		c.Assert(err, qt.IsNil)
+		single, err := pgdbtemplategoose.NewMigrationRunner(fstest.MapFS{
+			"00001_create_accounts.sql": auth["00001_create_accounts.sql"],
+			"00002_create_invoices.sql": billing["00002_create_invoices.sql"],
+		}).Fingerprint()
+		c.Assert(err, qt.IsNil)
+		c.Assert(merged, qt.Equals, single)
+	})
+
+	c.Run("Collisions", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			fstest.MapFS{
+				"00001_create_accounts.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+			},
+			pgdbtemplategoose.WithMigrationSources(
+				pgdbtemplategoose.MigrationSource{Name: "auth", FS: auth},
+				pgdbtemplategoose.MigrationSource{Name: "billing", FS: billing},
+				pgdbtemplategoose.MigrationSource{Name: "catalog", FS: fstest.MapFS{
+					"00002_create_products.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+				}},
+			),
+		)
+
+		_, err := runner.Lint()
+		c.Assert(errors.Is(err, pgdbtemplategoose.ErrSourceCollision), qt.IsTrue)
+		c.Assert(err, qt.ErrorMatches, `failed to collect migrations: `+
+			`migration sources collide: 00001_create_accounts.sql is in both migrationsFs and source "auth"\n`+
+			`migration sources collide: version 2 is used by 00002_create_invoices.sql in source "billing" and by 00002_create_products.sql in source "catalog"`)
+
+		// The run fails before the connection is used.
+		err = runner.RunMigrations(ctx, &sqlDBConnection{})
+		c.Assert(errors.Is(err, pgdbtemplategoose.ErrSourceCollision), qt.IsTrue)
+	})
+}
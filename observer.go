@@ -0,0 +1,104 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// runObserver follows the migrations of a run as they complete,
+// logging each of them and recording it into the run report.
+//
+// goose passes every migration result to its logger right after the
+// migration completes, so the observer is installed as the provider logger
+// and sees migrations while goose.Provider.Up is still running, without
+// changing how they are applied. goose's own output is routed to the
+// runner's logger at debug level.
+type runObserver struct {
+	runner *MigrationRunner
+	ctx    context.Context
+	// report is nil when nothing is recorded, e.g. for dry runs.
+	report *RunReport
+	// schema is the schema migrations are applied into, see WithSchemas.
+	schema string
+	// sampling samples locks while migrations run, see WithLockStats.
+	sampling *lockSampling
+	// seen holds results already recorded, as goose may report them
+	// and also return them.
+	seen map[*goose.MigrationResult]bool
+}
+
+// newRunObserver creates an observer recording migrations into report.
+func (r *MigrationRunner) newRunObserver(ctx context.Context, report *RunReport, schema string) *runObserver {
+	return &runObserver{
+		runner: r,
+		ctx:    ctx,
+		report: report,
+		schema: schema,
+		seen:   make(map[*goose.MigrationResult]bool),
+	}
+}
+
+func (o *runObserver) Printf(format string, v ...any) {
+	if len(v) == 1 {
+		if result, ok := v[0].(*goose.MigrationResult); ok {
+			o.migrated(result)
+		}
+	}
+	if o.runner.logger != nil {
+		o.runner.logger.Debug(strings.TrimSpace(fmt.Sprintf(format, v...)), slog.String("component", "goose"))
+	}
+}
+
+// Fatalf logs the message at error level and panics. Unlike the standard
+// logger goose uses by default, it does not exit, so that a library
+// never terminates the test binary behind the caller's back.
+func (o *runObserver) Fatalf(format string, v ...any) {
+	msg := strings.TrimSpace(fmt.Sprintf(format, v...))
+	if o.runner.logger != nil {
+		o.runner.logger.Error(msg, slog.String("component", "goose"))
+	}
+	panic(msg)
+}
+
+// migrated logs and records a migration that has just completed. goose
+// reports durations only, so the migration started that long ago.
+func (o *runObserver) migrated(result *goose.MigrationResult) {
+	if result == nil || result.Source == nil || o.seen[result] {
+		return
+	}
+	o.seen[result] = true
+
+	o.runner.logMigration(o.ctx, result, o.schema)
+	if o.report == nil {
+		return
+	}
+
+	o.report.recordStart(result, time.Now().Add(-result.Duration))
+	if o.sampling != nil {
+		if stats := o.sampling.cut(o.ctx); stats != nil {
+			if o.report.stats == nil {
+				o.report.stats = make(map[*goose.MigrationResult]*MigrationStats)
+			}
+			o.report.stats[result] = stats
+		}
+	}
+	if o.schema != "" {
+		if o.report.schemas == nil {
+			o.report.schemas = make(map[*goose.MigrationResult]string)
+		}
+		o.report.schemas[result] = o.schema
+	}
+}
+
+// finish records the results goose did not report while running: the failed
+// migration, or all of them if WithGooseOptions replaced the provider logger.
+func (o *runObserver) finish(results []*goose.MigrationResult, err error) {
+	for _, result := range gooseResults(results, err) {
+		o.migrated(result)
+	}
+}
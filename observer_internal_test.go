@@ -0,0 +1,43 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/pressly/goose/v3"
+)
+
+func TestRunObserver(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	report := &RunReport{}
+	observer := (&MigrationRunner{}).newRunObserver(context.Background(), report, "tenant_a")
+
+	first := &goose.MigrationResult{Source: &goose.Source{Version: 1}, Direction: "up", Duration: time.Hour}
+	second := &goose.MigrationResult{Source: &goose.Source{Version: 2}, Direction: "up", Duration: time.Second}
+	failed := &goose.MigrationResult{Source: &goose.Source{Version: 3}, Direction: "up", Error: errors.New("boom")}
+
+	// goose reports completed migrations through its logger.
+	before := time.Now()
+	observer.Printf("%s", first)
+	observer.Printf("goose: successfully migrated database, current version: %d", 1)
+	c.Assert(report.started, qt.HasLen, 1)
+	start := report.started[first]
+	c.Assert(start.Before(before.Add(-time.Hour).Add(time.Second)), qt.IsTrue)
+	c.Assert(start.After(before.Add(-time.Hour).Add(-time.Second)), qt.IsTrue)
+
+	// The failed migration and migrations goose did not report
+	// are recorded when the run returns, each only once.
+	observer.finish(nil, &goose.PartialError{Applied: []*goose.MigrationResult{first, second}, Failed: failed})
+	c.Assert(report.started, qt.HasLen, 3)
+	c.Assert(report.started[first], qt.Equals, start)
+	c.Assert(report.schemas, qt.DeepEquals, map[*goose.MigrationResult]string{
+		first:  "tenant_a",
+		second: "tenant_a",
+		failed: "tenant_a",
+	})
+}
@@ -1,6 +1,14 @@
 package pgdbtemplategoose
 
-import "github.com/pressly/goose/v3"
+import (
+	"io/fs"
+	"log/slog"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
 
 // Option configures the goose migration runner.
 type Option func(*MigrationRunner)
@@ -29,3 +37,529 @@ func WithGooseOptions(opts ...goose.ProviderOption) Option {
 		r.opts = append(r.opts, opts...)
 	}
 }
+
+// WithTargetVersion migrates the template to the given version instead of
+// the latest one, using goose's UpTo semantics: all pending migrations up to,
+// and including, version are applied.
+//
+// RunMigrations fails with an error wrapping goose.ErrVersionNotFound if
+// no migration with this version exists in the migrations filesystem.
+//
+// Example:
+//
+//	// Seed the template at version 4, then apply version 5 in the test.
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithTargetVersion(4),
+//	)
+func WithTargetVersion(version int64) Option {
+	return func(r *MigrationRunner) {
+		r.targetVersion = version
+	}
+}
+
+// WithReportCallback sets a function called with the report
+// of every RunMigrations call, both successful and failed.
+//
+// The same report is also available via MigrationRunner.LastReport.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithReportCallback(func(report *RunReport) {
+//	        for _, m := range report.Migrations {
+//	            log.Printf("applied %s in %s", m.Path, m.Duration)
+//	        }
+//	    }),
+//	)
+func WithReportCallback(callback func(*RunReport)) Option {
+	return func(r *MigrationRunner) {
+		r.reportCallback = callback
+	}
+}
+
+// WithGoMigration registers a Go migration running inside a transaction.
+//
+// Either function may be nil, in which case the version is recorded without
+// running anything for that direction. Versions must not clash with other
+// Go migrations or SQL files in the migrations filesystem.
+//
+// Registering Go migrations on the runner disables goose's global registry
+// for this runner, so migrations added with goose.AddMigrationContext and
+// similar functions are not applied. This keeps runners used in parallel
+// tests isolated from each other.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithGoMigration(3,
+//	        func(ctx context.Context, tx *sql.Tx) error {
+//	            _, err := tx.ExecContext(ctx, "INSERT INTO roles (name) VALUES ('admin')")
+//	            return err
+//	        },
+//	        nil,
+//	    ),
+//	)
+func WithGoMigration(version int64, up, down goose.GoMigrationContext) Option {
+	return func(r *MigrationRunner) {
+		r.goMigrations = append(r.goMigrations, goMigration{
+			version: version,
+			up:      goose.GoFunc{RunTx: up, Mode: goose.TransactionEnabled},
+			down:    goose.GoFunc{RunTx: down, Mode: goose.TransactionEnabled},
+		})
+	}
+}
+
+// WithGoMigrationNoTx registers a Go migration running outside a transaction,
+// e.g. for statements like CREATE INDEX CONCURRENTLY.
+//
+// See WithGoMigration for details on versions and the global registry.
+func WithGoMigrationNoTx(version int64, up, down goose.GoMigrationNoTxContext) Option {
+	return func(r *MigrationRunner) {
+		r.goMigrations = append(r.goMigrations, goMigration{
+			version: version,
+			up:      goose.GoFunc{RunDB: up, Mode: goose.TransactionDisabled},
+			down:    goose.GoFunc{RunDB: down, Mode: goose.TransactionDisabled},
+		})
+	}
+}
+
+// WithDisableGlobalRegistry prevents the runner from applying Go migrations
+// registered in goose's global registry.
+//
+// This is implied when Go migrations are registered on the runner
+// with WithGoMigration or WithGoMigrationNoTx.
+func WithDisableGlobalRegistry() Option {
+	return func(r *MigrationRunner) {
+		r.disableGlobalRegistry = true
+	}
+}
+
+// WithConnectionExtractor registers a function extracting *sql.DB
+// from connections the runner does not recognise out of the box.
+//
+// Extractors are tried in registration order before the built-in support
+// for SQLDBConnection, pgdbtemplate-pq and pgdbtemplate-pgx connections.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithConnectionExtractor(func(conn pgdbtemplate.DatabaseConnection) (*sql.DB, bool) {
+//	        traced, ok := conn.(*TracedConnection)
+//	        if !ok {
+//	            return nil, false
+//	        }
+//	        return traced.Inner.DB, true
+//	    }),
+//	)
+func WithConnectionExtractor(extractor ConnectionExtractor) Option {
+	return func(r *MigrationRunner) {
+		r.extractors = append(r.extractors, extractor)
+	}
+}
+
+// WithPgxNative runs SQL migrations directly over the pgxpool.Pool of
+// pgdbtemplate-pgx connections instead of bridging it to database/sql for goose.
+//
+// Migration files are parsed with goose's annotation rules (Up, Down,
+// StatementBegin/StatementEnd, NO TRANSACTION and ENVSUB) and applied versions
+// are recorded in a goose-compatible version table, so goose can keep
+// migrating such databases later.
+//
+// In this mode, Go migrations are not supported and options passed with
+// WithGooseOptions are ignored. Connections other than pgdbtemplate-pgx
+// still run through goose.
+func WithPgxNative() Option {
+	return func(r *MigrationRunner) {
+		r.pgxNative = true
+	}
+}
+
+// WithReuse makes RunMigrations inspect the goose version table first and
+// skip migrations if the database is already up to date: all migrations up
+// to the target version are applied and the stored fingerprint (see
+// MigrationRunner.Fingerprint) matches. The policy decides what happens
+// when the database diverges from the migrations.
+//
+// The fingerprint is stored as the comment of the goose version table after
+// every successful run. Databases without applied migrations are migrated
+// as usual. Reuse is not supported in pgx-native mode.
+//
+// pgdbtemplate.TemplateManager.Initialize never runs migrations on an
+// existing template, so a fixed-name template built from older migrations
+// would be used as is. Create the manager with NewTemplateManager to have
+// the fingerprint of an existing template checked: a stale template is
+// dropped and rebuilt with ReuseOrRebuild, and Initialize fails with
+// ErrDatabaseDiverged with ReuseOrFail.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithReuse(ReuseOrFail),
+//	)
+func WithReuse(policy ReusePolicy) Option {
+	return func(r *MigrationRunner) {
+		r.reusePolicy = policy
+	}
+}
+
+// WithRoundTripVerification makes RunMigrations exercise Down sections while
+// building the template: every pending migration is applied, rolled back and
+// applied again before moving on to the next one. The run fails with a
+// *RoundTripError naming the offending version if a rollback or the second
+// application fails, or if the schema after the second application differs
+// from the schema after the first one.
+//
+// Verification is not supported in pgx-native mode. Schemas are described
+// like by WithSchemaSnapshot, which requires PostgreSQL 11 or later.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithRoundTripVerification(),
+//	)
+func WithRoundTripVerification() Option {
+	return func(r *MigrationRunner) {
+		r.verifyRoundTrip = true
+	}
+}
+
+// WithSchemaSnapshot makes RunMigrations compare the migrated schema with
+// the golden file name in fsys. The schema is described from the catalog
+// as sorted lines, one per table column, index, constraint, view, sequence,
+// function, trigger, enum type and extension, with whitespace collapsed.
+// Objects created by extensions are described by the extension line alone,
+// and the goose version table is not part of the description.
+//
+// On mismatch, RunMigrations fails with ErrSchemaMismatch and a diff
+// of the lines missing from and added to the database. The description
+// relies on catalogs of PostgreSQL 11 or later.
+//
+// Example:
+//
+//	//go:embed testdata/schema.golden
+//	var snapshotFS embed.FS
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithSchemaSnapshot(snapshotFS, "testdata/schema.golden"),
+//	)
+func WithSchemaSnapshot(fsys fs.FS, name string) Option {
+	return func(r *MigrationRunner) {
+		if r.schemaSnapshot == nil {
+			r.schemaSnapshot = &schemaSnapshotConfig{}
+		}
+		r.schemaSnapshot.fsys = fsys
+		r.schemaSnapshot.name = name
+	}
+}
+
+// WithSchemaSnapshotUpdate makes RunMigrations write the migrated schema
+// to the file at path instead of comparing it, to regenerate the golden
+// file used with WithSchemaSnapshot.
+//
+// Example:
+//
+//	var update = flag.Bool("update", false, "update golden files")
+//
+//	opts := []Option{WithSchemaSnapshot(os.DirFS("testdata"), "schema.golden")}
+//	if *update {
+//	    opts = append(opts, WithSchemaSnapshotUpdate("testdata/schema.golden"))
+//	}
+func WithSchemaSnapshotUpdate(path string) Option {
+	return func(r *MigrationRunner) {
+		if r.schemaSnapshot == nil {
+			r.schemaSnapshot = &schemaSnapshotConfig{}
+		}
+		r.schemaSnapshot.updatePath = path
+	}
+}
+
+// WithSeeds makes RunMigrations load reference data from seedsFs after
+// migrations, so that it is cloned into every database created from the
+// template. Files are loaded in lexical order of their paths, all in a
+// single transaction:
+//   - .sql files are executed as is;
+//   - .yaml, .yml and .json files map table names to lists of rows,
+//     each row mapping column names to values. Nested objects and lists
+//     are inserted as JSON.
+//
+// Other files are ignored. Seeds are not supported in pgx-native mode.
+//
+// Example:
+//
+//	//go:embed seeds
+//	var seedsFS embed.FS
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithSeeds(seedsFS),
+//	)
+//
+// with seeds/01_roles.yaml:
+//
+//	roles:
+//	  - name: admin
+//	  - name: viewer
+func WithSeeds(seedsFs fs.FS) Option {
+	return func(r *MigrationRunner) {
+		r.seedsFs = seedsFs
+	}
+}
+
+// WithSessionLock serializes RunMigrations calls on the same database with
+// a PostgreSQL session-level advisory lock taken in that database, so that
+// when several processes migrate a shared database, only one migrates it
+// while the others wait. The lock is taken with goose's Postgres session
+// locker, or the same advisory lock in pgx-native mode.
+//
+// pgdbtemplate.TemplateManager only runs migrations in the process that
+// created the template database, so this lock alone does not stop other
+// processes from using a template that is still being migrated. Use
+// NewTemplateManager with a fixed template name to serialize template
+// creation as well.
+//
+// Attempts to take the lock are repeated every retryInterval for up to
+// timeout. goose retries in whole seconds, so both are rounded up
+// to the nearest second. Both must be greater than zero.
+//
+// Advisory locks are scoped to the current database, so runs migrating
+// different databases never wait for each other.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithSessionLock(2*time.Minute, time.Second),
+//	)
+func WithSessionLock(timeout, retryInterval time.Duration) Option {
+	return func(r *MigrationRunner) {
+		r.sessionLock = &sessionLockConfig{
+			timeout:       timeout,
+			retryInterval: retryInterval,
+		}
+	}
+}
+
+// WithLogger sets a structured logger for migration runs. RunMigrations logs
+// its start, one record per applied or rolled back migration with its
+// version, file, direction, duration and result as soon as the migration
+// completes, and its finish.
+// Failed migrations and runs are logged at error level, everything else
+// at info level.
+//
+// goose's own output is routed to the same logger at debug level,
+// including every executed statement.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithLogger(slog.Default()),
+//	)
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *MigrationRunner) {
+		r.logger = logger
+	}
+}
+
+// WithTracerProvider instruments RunMigrations with OpenTelemetry tracing.
+// Every run creates a "pgdbtemplategoose.RunMigrations" span with a
+// "pgdbtemplategoose.migration" child span per applied or rolled back
+// migration. The run span is passed down in the context, so spans of
+// instrumented database drivers become its children.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithTracerProvider(otel.GetTracerProvider()),
+//	)
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(r *MigrationRunner) {
+		r.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider instruments RunMigrations with OpenTelemetry metrics:
+//   - pgdbtemplate.goose.migration.duration, a histogram of migration
+//     durations in seconds;
+//   - pgdbtemplate.goose.migrations.applied and
+//     pgdbtemplate.goose.migrations.failed, counters of migrations.
+//
+// All of them have version, direction and type attributes.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithMeterProvider(otel.GetMeterProvider()),
+//	)
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(r *MigrationRunner) {
+		r.meterProvider = provider
+	}
+}
+
+// WithLint makes RunMigrations check migrations with MigrationRunner.Lint
+// before touching the database. If any problems are found, it fails with
+// a *LintError listing all of them.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithLint(),
+//	)
+func WithLint() Option {
+	return func(r *MigrationRunner) {
+		r.lintBeforeRun = true
+	}
+}
+
+// WithPolicy makes RunMigrations inspect statements of SQL migrations with
+// the rules before touching the database, see MigrationRunner.CheckPolicy.
+// Violations of PolicyFail rules fail the run with a *PolicyError, while
+// violations of PolicyWarn rules are reported in RunReport.PolicyWarnings.
+//
+// Built-in rules are returned by DefaultPolicyRules; custom rules only need
+// a name and a Check function. Calling WithPolicy again adds more rules.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithPolicy(DefaultPolicyRules(PolicyFail)...),
+//	    WithPolicy(PolicyRule{
+//	        Name:     "no-drop-table",
+//	        Severity: PolicyWarn,
+//	        Check: func(stmt PolicyStatement) string {
+//	            if strings.HasPrefix(strings.ToUpper(stmt.SQL), "DROP TABLE") {
+//	                return "dropping tables loses data"
+//	            }
+//	            return ""
+//	        },
+//	    }),
+//	)
+func WithPolicy(rules ...PolicyRule) Option {
+	return func(r *MigrationRunner) {
+		r.policyRules = append(r.policyRules, rules...)
+	}
+}
+
+// WithLockStats makes RunMigrations sample pg_locks from a separate
+// connection every interval while migrations run, attaching the lock modes
+// and relations observed while each of them ran to MigrationResult.Stats.
+// Migrations are applied by goose as usual, so goose options such as
+// goose.WithAllowOutofOrder and the session lock of WithSessionLock apply.
+// Statement timing comes from pg_stat_statements when the extension is
+// installed in the database, and from sampling pg_stat_activity otherwise.
+// Locks held for less than the interval may be missed; zero uses 10ms.
+//
+// The sampler sees every session busy in the database, so the template
+// database should not be used by anything else during the run.
+//
+// The sampler needs a connection of its own next to the one goose uses.
+// Sampling is skipped, with a warning logged through WithLogger, for
+// *sql.DB pools limited to a single connection with SetMaxOpenConns(1);
+// MigrationResult.Stats is nil then. pgxpool limits are not visible
+// through database/sql, so pgdbtemplate-pgx pools must allow at least
+// two connections.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(migrationsFs, WithLockStats(5*time.Millisecond))
+//	// ... after RunMigrations
+//	for _, migration := range runner.LastReport().Migrations {
+//	    for _, lock := range migration.Stats.Locks {
+//	        fmt.Println(migration.Path, lock.Relation, lock.Mode)
+//	    }
+//	}
+func WithLockStats(interval time.Duration) Option {
+	return func(r *MigrationRunner) {
+		r.lockStats = &lockStatsConfig{interval: interval}
+	}
+}
+
+// WithMigrationSources merges migrations from several filesystems, e.g. one
+// embed.FS per bounded context, into a single ordered goose run. Migrations
+// from migrationsFs, which may be nil, are merged with them.
+//
+// Versions and migration file names must be unique across all sources:
+// RunMigrations, DryRun, Lint and Fingerprint fail with ErrSourceCollision
+// listing every collision otherwise. MigrationResult.Source and
+// PendingMigration.Source tell which source a migration came from.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(nil, WithMigrationSources(
+//	    MigrationSource{Name: "auth", FS: authMigrations},
+//	    MigrationSource{Name: "billing", FS: billingMigrations},
+//	    MigrationSource{Name: "catalog", FS: catalogMigrations},
+//	))
+func WithMigrationSources(sources ...MigrationSource) Option {
+	return func(r *MigrationRunner) {
+		r.migrationSources = append(r.migrationSources, sources...)
+	}
+}
+
+// WithTableName sets the name of goose's version table, goose_db_version
+// by default. Runners applying independent migration sets to the same
+// database, e.g. stages of a Pipeline, need distinct version tables.
+// The name must be a lower-case unquoted identifier; use WithTableSchema
+// to place the table in a schema.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    vendoredMigrationsFs,
+//	    WithTableName("vendor_goose_db_version"),
+//	)
+func WithTableName(name string) Option {
+	return func(r *MigrationRunner) {
+		r.tableName = name
+	}
+}
+
+// WithSchemas applies the migrations into each of the schemas in turn,
+// e.g. one per tenant, instead of the default search_path. Every schema is
+// created if needed and migrated over a single connection with search_path
+// set to the schema alone, so unqualified names in migrations resolve to it,
+// and records versions in its own schema-qualified version table.
+// Objects from other schemas, such as extensions in public, must be
+// qualified in migrations.
+//
+// Schema names must be lower-case unquoted identifiers. Seeds are loaded
+// into every schema. Per-schema runs are not supported in pgx-native mode,
+// nor with schema snapshots, lock statistics or DryRun.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(migrationsFs, WithSchemas("tenant_a", "tenant_b"))
+func WithSchemas(schemas ...string) Option {
+	return func(r *MigrationRunner) {
+		r.schemas = append(r.schemas, schemas...)
+	}
+}
+
+// WithTableSchema places goose's version table in the given schema instead
+// of the first schema of the search_path, e.g. to mirror production databases
+// keeping it in an "ops" schema. The schema is created before migrating if it
+// does not exist yet, and RunMigrations fails if that is not possible.
+// The name must be a lower-case unquoted identifier.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithTableSchema("ops"),
+//	    WithTableName("schema_migrations"),
+//	)
+func WithTableSchema(schema string) Option {
+	return func(r *MigrationRunner) {
+		r.tableSchema = schema
+	}
+}
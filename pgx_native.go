@@ -0,0 +1,262 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pressly/goose/v3"
+)
+
+// pgxExecutor is implemented by both *pgx.Conn and pgx.Tx.
+type pgxExecutor interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// runPgxNative applies pending SQL migrations directly over the pool,
+// without the database/sql bridge goose requires.
+//
+// The version table has the same layout and contents as goose's own,
+// so templates built this way can later be migrated by goose and vice versa.
+func (r *MigrationRunner) runPgxNative(ctx context.Context, pool *pgxpool.Pool, report *RunReport) (_ []*goose.MigrationResult, err error) {
+	if len(r.goMigrations) > 0 {
+		return nil, errors.New("pgx-native mode does not support Go migrations")
+	}
+
+	sources, err := collectSources(r.migrationsFs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect migrations: %w", err)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("failed to collect migrations: %w", goose.ErrNoMigrations)
+	}
+	for _, source := range sources {
+		if source.Type == goose.TypeGo {
+			return nil, fmt.Errorf("pgx-native mode does not support Go migrations: %s", source.Path)
+		}
+	}
+
+	target, err := r.nativeTargetVersion(sources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run goose migrations: %w", err)
+	}
+
+	// Use a single connection, like goose does, so that session state
+	// set by NO TRANSACTION migrations is preserved between them.
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if r.sessionLock != nil {
+		unlock, lockErr := r.sessionLock.lockNative(ctx, conn.Conn())
+		if lockErr != nil {
+			return nil, lockErr
+		}
+		defer func() {
+			err = errors.Join(err, unlock(context.WithoutCancel(ctx)))
+		}()
+	}
+
+	results, err := r.applyNative(ctx, conn.Conn(), sources, target, r.newRunObserver(ctx, report, ""))
+	if err != nil {
+		return results, fmt.Errorf("failed to run goose migrations: %w", err)
+	}
+	return results, nil
+}
+
+// nativeTargetVersion returns the version to migrate to in pgx-native mode.
+func (r *MigrationRunner) nativeTargetVersion(sources []goose.Source) (int64, error) {
+	latest := sources[len(sources)-1].Version
+	if r.targetVersion == 0 {
+		return latest, nil
+	}
+	if r.targetVersion < 0 {
+		return 0, fmt.Errorf("invalid target version %d: must be greater than 0", r.targetVersion)
+	}
+	for _, source := range sources {
+		if source.Version == r.targetVersion {
+			return r.targetVersion, nil
+		}
+	}
+	return 0, fmt.Errorf("target version %d not found in migrations: %w", r.targetVersion, goose.ErrVersionNotFound)
+}
+
+// applyNative applies pending migrations up to, and including, target.
+func (r *MigrationRunner) applyNative(ctx context.Context, conn *pgx.Conn, sources []goose.Source, target int64, observer *runObserver) ([]*goose.MigrationResult, error) {
+	if err := r.ensureNativeTableSchema(ctx, conn); err != nil {
+		return nil, err
+	}
+	if err := r.ensureNativeVersionTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	applied, err := r.nativeAppliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	var maxApplied int64
+	for version := range applied {
+		maxApplied = max(maxApplied, version)
+	}
+
+	// Collect pending migrations. Like goose by default,
+	// refuse to apply migrations older than the latest applied one.
+	var pending []goose.Source
+	var missing []int64
+	for _, source := range sources {
+		if applied[source.Version] || source.Version > target {
+			continue
+		}
+		if source.Version < maxApplied {
+			missing = append(missing, source.Version)
+			continue
+		}
+		pending = append(pending, source)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("found %d missing (out-of-order) migration(s): %v", len(missing), missing)
+	}
+
+	// Parse everything up front so that a broken file
+	// does not leave the database half-migrated.
+	parsed := make([]*sqlMigration, len(pending))
+	for i, source := range pending {
+		if parsed[i], err = parseSQLMigrationFile(r.migrationsFs, source.Path); err != nil {
+			return nil, fmt.Errorf("failed to prepare migration %s: %w", source.Path, err)
+		}
+	}
+
+	var results []*goose.MigrationResult
+	for i, source := range pending {
+		source := source
+		result := &goose.MigrationResult{
+			Source:    &source,
+			Direction: "up",
+			Empty:     len(parsed[i].up) == 0,
+		}
+
+		start := time.Now()
+		err := r.applyNativeMigration(ctx, conn, source.Version, parsed[i])
+		result.Duration = time.Since(start)
+		if err != nil {
+			result.Error = err
+			observer.migrated(result)
+			return nil, &goose.PartialError{
+				Applied: results,
+				Failed:  result,
+				Err:     err,
+			}
+		}
+		observer.migrated(result)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// applyNativeMigration runs the up statements of a single migration
+// and records its version, inside a transaction unless the migration
+// is annotated with NO TRANSACTION.
+func (r *MigrationRunner) applyNativeMigration(ctx context.Context, conn *pgx.Conn, version int64, migration *sqlMigration) (err error) {
+	if !migration.useTx {
+		return runNativeStatements(ctx, conn, r.versionTable(), version, migration.up)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback(ctx))
+		}
+	}()
+
+	if err = runNativeStatements(ctx, tx, r.versionTable(), version, migration.up); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// runNativeStatements executes statements and inserts the version row.
+func runNativeStatements(ctx context.Context, exec pgxExecutor, versionTable string, version int64, statements []sqlStatement) error {
+	for _, stmt := range statements {
+		if _, err := exec.Exec(ctx, stmt.sql); err != nil {
+			return fmt.Errorf("failed to execute SQL query %q: %w", stmt.sql, err)
+		}
+	}
+	query := fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES ($1, $2)", versionTable)
+	if _, err := exec.Exec(ctx, query, version, true); err != nil {
+		return fmt.Errorf("failed to insert version %d: %w", version, err)
+	}
+	return nil
+}
+
+// ensureNativeVersionTable creates goose's version table
+// with its initial zero version unless it already exists.
+func (r *MigrationRunner) ensureNativeVersionTable(ctx context.Context, conn *pgx.Conn) (err error) {
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", r.versionTable()).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check if version table exists: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create version table: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback(ctx))
+		}
+	}()
+
+	// Same layout as goose's Postgres store.
+	createQuery := fmt.Sprintf(`CREATE TABLE %s (
+		id integer PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+		version_id bigint NOT NULL,
+		is_applied boolean NOT NULL,
+		tstamp timestamp NOT NULL DEFAULT now()
+	)`, r.versionTable())
+	if _, err = tx.Exec(ctx, createQuery); err != nil {
+		return fmt.Errorf("failed to create version table: %w", err)
+	}
+	insertQuery := fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES ($1, $2)", r.versionTable())
+	if _, err = tx.Exec(ctx, insertQuery, 0, true); err != nil {
+		return fmt.Errorf("failed to insert zero version: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// nativeAppliedVersions returns the versions recorded as applied in the version table.
+func (r *MigrationRunner) nativeAppliedVersions(ctx context.Context, conn *pgx.Conn) (map[int64]bool, error) {
+	// Like goose, the latest row of a version tells whether it is applied:
+	// older goose versions record rollbacks as rows with is_applied false.
+	rows, err := conn.Query(ctx, fmt.Sprintf(`
+		SELECT version_id FROM (
+			SELECT DISTINCT ON (version_id) version_id, is_applied
+			FROM %s
+			ORDER BY version_id, id DESC
+		) latest
+		WHERE is_applied`, r.versionTable()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	versions, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+
+	applied := make(map[int64]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+	return applied, nil
+}
@@ -0,0 +1,228 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepgx "github.com/andrei-polukhin/pgdbtemplate-pgx"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMigrationRunnerPgxNative(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	migrations := map[string]string{
+		"00001_create_orders.sql": `-- +goose Up
+CREATE TABLE goose_native_orders (
+    id SERIAL PRIMARY KEY,
+    total INTEGER NOT NULL
+);
+
+-- +goose StatementBegin
+CREATE FUNCTION goose_native_total() RETURNS bigint AS $$
+BEGIN
+    RETURN (SELECT COALESCE(SUM(total), 0) FROM goose_native_orders);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION goose_native_total();
+DROP TABLE goose_native_orders;
+`,
+		"00002_index_orders.sql": `-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY goose_native_orders_total_idx ON goose_native_orders (total);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY goose_native_orders_total_idx;
+`,
+		"00003_seed_orders.sql": `-- +goose Up
+INSERT INTO goose_native_orders (total) VALUES (10), (32);
+
+-- +goose Down
+DELETE FROM goose_native_orders;
+`,
+	}
+
+	// initialize builds a template with the runner and returns a test database.
+	initialize := func(c *qt.C, runner pgdbtemplate.MigrationRunner) *pgdbtemplatepgx.DatabaseConnection {
+		provider := pgdbtemplatepgx.NewConnectionProvider(testConnectionStringFunc)
+		c.Cleanup(provider.Close)
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() {
+			testDB.Close()
+			tm.DropTestDatabase(ctx, dbName)
+		})
+		return testDB.(*pgdbtemplatepgx.DatabaseConnection)
+	}
+
+	c.Run("Applies SQL migrations over pgx", func(c *qt.C) {
+		c.Parallel()
+
+		migrationsFs := writeMigrations(c, migrations)
+		runner := pgdbtemplategoose.NewMigrationRunner(migrationsFs, pgdbtemplategoose.WithPgxNative())
+		pgxConn := initialize(c, runner)
+
+		var total int64
+		err := pgxConn.Pool.QueryRow(ctx, "SELECT goose_native_total()").Scan(&total)
+		c.Assert(err, qt.IsNil)
+		c.Assert(total, qt.Equals, int64(42))
+
+		var indexName string
+		err = pgxConn.Pool.QueryRow(ctx, `
+			SELECT indexname
+			FROM pg_indexes
+			WHERE indexname = 'goose_native_orders_total_idx'
+		`).Scan(&indexName)
+		c.Assert(err, qt.IsNil)
+
+		report := runner.LastReport()
+		c.Assert(report.Error, qt.IsNil)
+		c.Assert(report.Migrations, qt.HasLen, 3)
+		c.Assert(report.Migrations[1].Path, qt.Equals, "00002_index_orders.sql")
+
+		// The version table must be usable by goose itself:
+		// nothing is left to apply on the cloned database.
+		db, err := sql.Open("pgx", testConnectionStringFunc(pgxConn.Pool.Config().ConnConfig.Database))
+		c.Assert(err, qt.IsNil)
+		defer db.Close()
+
+		gooseRunner := pgdbtemplategoose.NewMigrationRunner(migrationsFs)
+		err = gooseRunner.RunMigrations(ctx, &sqlDBConnection{db: db})
+		c.Assert(err, qt.IsNil)
+		c.Assert(gooseRunner.LastReport().Migrations, qt.HasLen, 0)
+	})
+
+	c.Run("Target version", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithPgxNative(),
+			pgdbtemplategoose.WithTargetVersion(2),
+		)
+		pgxConn := initialize(c, runner)
+
+		var version int64
+		err := pgxConn.Pool.QueryRow(ctx, "SELECT MAX(version_id) FROM goose_db_version").Scan(&version)
+		c.Assert(err, qt.IsNil)
+		c.Assert(version, qt.Equals, int64(2))
+
+		var count int
+		err = pgxConn.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM goose_native_orders").Scan(&count)
+		c.Assert(err, qt.IsNil)
+		c.Assert(count, qt.Equals, 0)
+	})
+
+	c.Run("Rolled back versions are applied again", func(c *qt.C) {
+		c.Parallel()
+
+		migrationsFs := writeMigrations(c, migrations)
+		pgxConn := initialize(c, pgdbtemplategoose.NewMigrationRunner(
+			migrationsFs,
+			pgdbtemplategoose.WithPgxNative(),
+			pgdbtemplategoose.WithTargetVersion(2),
+		))
+
+		// Record a rollback of version 2 the way older goose versions do.
+		_, err := pgxConn.Pool.Exec(ctx, "DROP INDEX goose_native_orders_total_idx")
+		c.Assert(err, qt.IsNil)
+		_, err = pgxConn.Pool.Exec(ctx, "INSERT INTO goose_db_version (version_id, is_applied) VALUES (2, false)")
+		c.Assert(err, qt.IsNil)
+
+		runner := pgdbtemplategoose.NewMigrationRunner(migrationsFs, pgdbtemplategoose.WithPgxNative())
+		err = runner.RunMigrations(ctx, pgxConn)
+		c.Assert(err, qt.IsNil)
+
+		var applied []int64
+		for _, migration := range runner.LastReport().Migrations {
+			applied = append(applied, migration.Version)
+		}
+		c.Assert(applied, qt.DeepEquals, []int64{2, 3})
+	})
+
+	c.Run("Go migrations are rejected", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithPgxNative(),
+			pgdbtemplategoose.WithGoMigration(4, nil, nil),
+		)
+		provider := pgdbtemplatepgx.NewConnectionProvider(testConnectionStringFunc)
+		defer provider.Close()
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.ErrorMatches, ".*pgx-native mode does not support Go migrations")
+	})
+
+	c.Run("Invalid migration file", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, map[string]string{
+				"00001_broken.sql": `-- +goose Up
+-- +goose StatementBegin
+SELECT 1;
+`,
+			}),
+			pgdbtemplategoose.WithPgxNative(),
+		)
+		provider := pgdbtemplatepgx.NewConnectionProvider(testConnectionStringFunc)
+		defer provider.Close()
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.ErrorMatches, ".*failed to prepare migration 00001_broken.sql: .*missing '-- \\+goose StatementEnd' annotation")
+	})
+}
+
+// sqlDBConnection is a minimal SQLDBConnection backed by *sql.DB.
+type sqlDBConnection struct {
+	db *sql.DB
+}
+
+func (c *sqlDBConnection) ExecContext(ctx context.Context, query string, args ...any) (any, error) {
+	return c.db.ExecContext(ctx, query, args...)
+}
+
+func (c *sqlDBConnection) QueryRowContext(ctx context.Context, query string, args ...any) pgdbtemplate.Row {
+	return c.db.QueryRowContext(ctx, query, args...)
+}
+
+func (c *sqlDBConnection) Close() error {
+	return c.db.Close()
+}
+
+func (c *sqlDBConnection) SQLDB() *sql.DB {
+	return c.db
+}
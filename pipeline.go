@@ -0,0 +1,170 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+)
+
+// PipelineStage is a named migration runner run by a Pipeline.
+type PipelineStage struct {
+	// Name identifies the stage in reports and errors.
+	Name string
+	// Runner applies the migrations of the stage.
+	Runner *MigrationRunner
+}
+
+// StageError is returned by Pipeline.RunMigrations when a stage fails.
+type StageError struct {
+	// Stage is the name of the failed stage.
+	Stage string
+	// Err is the error the stage failed with.
+	Err error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("migration stage %q failed: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// StageReport is the report of a single pipeline stage.
+type StageReport struct {
+	// Name is the name of the stage.
+	Name string
+	// Report is the report of the stage runner.
+	Report *RunReport
+}
+
+// PipelineReport describes a single Pipeline.RunMigrations call.
+type PipelineReport struct {
+	// Stages lists reports of the stages that ran, in order,
+	// including the failed one, if any.
+	Stages []StageReport
+	// Duration is the total time RunMigrations took.
+	Duration time.Duration
+	// Error is the error RunMigrations returned, if any.
+	Error error
+}
+
+// Pipeline implements pgdbtemplate.MigrationRunner by running several
+// MigrationRunner instances in order, e.g. a vendored library's schema,
+// then extensions, then the application schema with seeds.
+//
+// Every stage must use its own goose version table, see WithTableName.
+// Stage runners must not be used elsewhere while the pipeline runs.
+type Pipeline struct {
+	stages []PipelineStage
+
+	mu         sync.Mutex
+	lastReport *PipelineReport
+}
+
+// NewPipeline creates a pipeline running the stages in the given order.
+//
+// Example:
+//
+//	pipeline := pgdbtemplategoose.NewPipeline(
+//	    pgdbtemplategoose.PipelineStage{
+//	        Name:   "vendor",
+//	        Runner: pgdbtemplategoose.NewMigrationRunner(vendorFs, pgdbtemplategoose.WithTableName("vendor_goose_db_version")),
+//	    },
+//	    pgdbtemplategoose.PipelineStage{
+//	        Name:   "app",
+//	        Runner: pgdbtemplategoose.NewMigrationRunner(appFs, pgdbtemplategoose.WithSeeds(seedsFs)),
+//	    },
+//	)
+func NewPipeline(stages ...PipelineStage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// RunMigrations implements pgdbtemplate.MigrationRunner.RunMigrations.
+//
+// It runs the stages in order and stops at the first failing one,
+// returning a *StageError naming it. A report of the run is available
+// afterwards via LastReport.
+func (p *Pipeline) RunMigrations(ctx context.Context, conn pgdbtemplate.DatabaseConnection) error {
+	start := time.Now()
+	report := &PipelineReport{}
+	err := p.runMigrations(ctx, conn, report)
+
+	report.Duration = time.Since(start)
+	report.Error = err
+	p.mu.Lock()
+	p.lastReport = report
+	p.mu.Unlock()
+	return err
+}
+
+// runMigrations runs the stages, recording their reports.
+func (p *Pipeline) runMigrations(ctx context.Context, conn pgdbtemplate.DatabaseConnection, report *PipelineReport) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	for _, stage := range p.stages {
+		stageReport, err := stage.Runner.run(ctx, conn)
+		report.Stages = append(report.Stages, StageReport{Name: stage.Name, Report: stageReport})
+		if err != nil {
+			return &StageError{Stage: stage.Name, Err: err}
+		}
+	}
+	return nil
+}
+
+// validate checks that stages can be told apart
+// and do not share version tables.
+func (p *Pipeline) validate() error {
+	names := make(map[string]bool)
+	tables := make(map[string]string)
+	for i, stage := range p.stages {
+		if stage.Name == "" {
+			return fmt.Errorf("pipeline stage %d has no name", i)
+		}
+		if names[stage.Name] {
+			return fmt.Errorf("duplicate pipeline stage %q", stage.Name)
+		}
+		names[stage.Name] = true
+
+		if stage.Runner == nil {
+			return fmt.Errorf("pipeline stage %q has no runner", stage.Name)
+		}
+		// Compare the tables runs actually use, e.g. one per schema
+		// with WithSchemas.
+		for _, table := range stage.Runner.versionTables() {
+			if other, ok := tables[table]; ok {
+				return fmt.Errorf("pipeline stages %q and %q share version table %q: use WithTableName",
+					other, stage.Name, table)
+			}
+			tables[table] = stage.Name
+		}
+	}
+	return nil
+}
+
+// LastReport returns the report of the most recent RunMigrations call,
+// or nil if migrations have not been run yet.
+func (p *Pipeline) LastReport() *PipelineReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastReport
+}
+
+// Close closes the runners of all stages.
+func (p *Pipeline) Close() error {
+	var errs error
+	for _, stage := range p.stages {
+		if stage.Runner == nil {
+			continue
+		}
+		if err := stage.Runner.Close(); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("failed to close stage %q: %w", stage.Name, err))
+		}
+	}
+	return errs
+}
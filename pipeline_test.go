@@ -0,0 +1,161 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestPipeline(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	// Both migration sets start at version 1.
+	vendorFs := fstest.MapFS{
+		"00001_create_jobs.sql": {Data: []byte("-- +goose Up\nCREATE TABLE goose_pipeline_jobs (id SERIAL PRIMARY KEY);\n")},
+	}
+	appFs := fstest.MapFS{
+		"00001_create_tasks.sql": {Data: []byte("-- +goose Up\nCREATE TABLE goose_pipeline_tasks (id SERIAL PRIMARY KEY, job_id int REFERENCES goose_pipeline_jobs (id));\n")},
+		"00002_add_title.sql":    {Data: []byte("-- +goose Up\nALTER TABLE goose_pipeline_tasks ADD COLUMN title TEXT;\n")},
+	}
+
+	// initialize builds a template with the pipeline.
+	initialize := func(c *qt.C, pipeline *pgdbtemplategoose.Pipeline) (*pgdbtemplate.TemplateManager, error) {
+		provider := pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    pipeline,
+		})
+		c.Assert(err, qt.IsNil)
+
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+		return tm, tm.Initialize(ctx)
+	}
+
+	c.Run("Stages in order", func(c *qt.C) {
+		c.Parallel()
+
+		pipeline := pgdbtemplategoose.NewPipeline(
+			pgdbtemplategoose.PipelineStage{
+				Name:   "vendor",
+				Runner: pgdbtemplategoose.NewMigrationRunner(vendorFs, pgdbtemplategoose.WithTableName("goose_pipeline_vendor_version")),
+			},
+			pgdbtemplategoose.PipelineStage{
+				Name:   "app",
+				Runner: pgdbtemplategoose.NewMigrationRunner(appFs),
+			},
+		)
+		defer pipeline.Close()
+
+		tm, err := initialize(c, pipeline)
+		c.Assert(err, qt.IsNil)
+
+		report := pipeline.LastReport()
+		c.Assert(report.Error, qt.IsNil)
+		var stages []string
+		var migrations []int
+		for _, stage := range report.Stages {
+			stages = append(stages, stage.Name)
+			migrations = append(migrations, len(stage.Report.Migrations))
+		}
+		c.Assert(stages, qt.DeepEquals, []string{"vendor", "app"})
+		c.Assert(migrations, qt.DeepEquals, []int{1, 2})
+
+		// Every stage has its own version table.
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		defer func() {
+			testDB.Close()
+			tm.DropTestDatabase(ctx, dbName)
+		}()
+		var vendorVersion, appVersion int64
+		err = testDB.QueryRowContext(ctx, "SELECT max(version_id) FROM goose_pipeline_vendor_version").Scan(&vendorVersion)
+		c.Assert(err, qt.IsNil)
+		err = testDB.QueryRowContext(ctx, "SELECT max(version_id) FROM goose_db_version").Scan(&appVersion)
+		c.Assert(err, qt.IsNil)
+		c.Assert([]int64{vendorVersion, appVersion}, qt.DeepEquals, []int64{1, 2})
+	})
+
+	c.Run("Failing stage", func(c *qt.C) {
+		c.Parallel()
+
+		pipeline := pgdbtemplategoose.NewPipeline(
+			pgdbtemplategoose.PipelineStage{
+				Name:   "vendor",
+				Runner: pgdbtemplategoose.NewMigrationRunner(vendorFs, pgdbtemplategoose.WithTableName("goose_pipeline_vendor_version")),
+			},
+			pgdbtemplategoose.PipelineStage{
+				Name: "extensions",
+				Runner: pgdbtemplategoose.NewMigrationRunner(fstest.MapFS{
+					"00001_broken.sql": {Data: []byte("-- +goose Up\nCREATE EXTENSION goose_pipeline_missing;\n")},
+				}, pgdbtemplategoose.WithTableName("goose_pipeline_extensions_version")),
+			},
+			pgdbtemplategoose.PipelineStage{
+				Name:   "app",
+				Runner: pgdbtemplategoose.NewMigrationRunner(appFs),
+			},
+		)
+		defer pipeline.Close()
+
+		_, err := initialize(c, pipeline)
+		var stageErr *pgdbtemplategoose.StageError
+		c.Assert(errors.As(err, &stageErr), qt.IsTrue)
+		c.Assert(stageErr.Stage, qt.Equals, "extensions")
+
+		// The stage after the failed one did not run.
+		report := pipeline.LastReport()
+		c.Assert(report.Stages, qt.HasLen, 2)
+		c.Assert(report.Stages[1].Report.Migrations, qt.HasLen, 1)
+		c.Assert(report.Stages[1].Report.Migrations[0].Error, qt.IsNotNil)
+	})
+
+	c.Run("Invalid stages", func(c *qt.C) {
+		c.Parallel()
+
+		for _, test := range []struct {
+			about  string
+			stages []pgdbtemplategoose.PipelineStage
+			err    string
+		}{{
+			about:  "missing name",
+			stages: []pgdbtemplategoose.PipelineStage{{Runner: pgdbtemplategoose.NewMigrationRunner(vendorFs)}},
+			err:    "pipeline stage 0 has no name",
+		}, {
+			about: "duplicate name",
+			stages: []pgdbtemplategoose.PipelineStage{
+				{Name: "app", Runner: pgdbtemplategoose.NewMigrationRunner(vendorFs, pgdbtemplategoose.WithTableName("vendor_version"))},
+				{Name: "app", Runner: pgdbtemplategoose.NewMigrationRunner(appFs)},
+			},
+			err: `duplicate pipeline stage "app"`,
+		}, {
+			about:  "missing runner",
+			stages: []pgdbtemplategoose.PipelineStage{{Name: "app"}},
+			err:    `pipeline stage "app" has no runner`,
+		}, {
+			about: "shared version table",
+			stages: []pgdbtemplategoose.PipelineStage{
+				{Name: "vendor", Runner: pgdbtemplategoose.NewMigrationRunner(vendorFs)},
+				{Name: "app", Runner: pgdbtemplategoose.NewMigrationRunner(appFs)},
+			},
+			err: `pipeline stages "vendor" and "app" share version table "goose_db_version": use WithTableName`,
+		}, {
+			about: "shared per-schema version table",
+			stages: []pgdbtemplategoose.PipelineStage{
+				{Name: "vendor", Runner: pgdbtemplategoose.NewMigrationRunner(vendorFs, pgdbtemplategoose.WithSchemas("tenant_a", "tenant_b"))},
+				{Name: "app", Runner: pgdbtemplategoose.NewMigrationRunner(appFs, pgdbtemplategoose.WithSchemas("tenant_b"))},
+			},
+			err: `pipeline stages "vendor" and "app" share version table "tenant_b.goose_db_version": use WithTableName`,
+		}} {
+			// The check fails before the connection is used.
+			err := pgdbtemplategoose.NewPipeline(test.stages...).RunMigrations(ctx, &sqlDBConnection{})
+			c.Assert(err, qt.ErrorMatches, test.err, qt.Commentf(test.about))
+		}
+	})
+}
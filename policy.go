@@ -0,0 +1,311 @@
+package pgdbtemplategoose
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+)
+
+// PolicySeverity is what happens when a policy rule is violated.
+type PolicySeverity int
+
+const (
+	// PolicyFail makes RunMigrations fail with a *PolicyError.
+	PolicyFail PolicySeverity = iota
+	// PolicyWarn reports the violation in RunReport.PolicyWarnings
+	// and logs it, see WithLogger.
+	PolicyWarn
+)
+
+func (s PolicySeverity) String() string {
+	if s == PolicyWarn {
+		return "warn"
+	}
+	return "fail"
+}
+
+// PolicyStatement is a statement of an Up section inspected by policy rules.
+type PolicyStatement struct {
+	// File is the migration file of the statement.
+	File string
+	// Line is the 1-based line the statement starts at.
+	Line int
+	// SQL is the statement text.
+	SQL string
+	// UseTx is false if the migration is annotated with NO TRANSACTION.
+	UseTx bool
+	// CreatedTables are lower-cased names of tables created by earlier
+	// statements of the same migration, as written in CREATE TABLE.
+	// Operations on them cannot block other sessions.
+	CreatedTables []string
+}
+
+// createdTable reports whether the table was created earlier
+// in the same migration.
+func (s PolicyStatement) createdTable(table string) bool {
+	for _, created := range s.CreatedTables {
+		if created == table {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyRule is a check of migration statements, see WithPolicy.
+type PolicyRule struct {
+	// Name identifies the rule in violations and suppression comments.
+	Name string
+	// Severity is what happens when the rule is violated.
+	Severity PolicySeverity
+	// Check returns a description of the problem with the statement,
+	// or an empty string if the statement complies with the rule.
+	Check func(stmt PolicyStatement) string
+}
+
+// PolicyViolation is a statement violating a policy rule.
+type PolicyViolation struct {
+	Rule     string
+	Severity PolicySeverity
+	File     string
+	Line     int
+	Message  string
+}
+
+// String formats the violation as "file:line: rule: message".
+func (v PolicyViolation) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", v.File, v.Line, v.Rule, v.Message)
+}
+
+// PolicyError is returned by RunMigrations when migrations
+// violate policy rules with the PolicyFail severity.
+type PolicyError struct {
+	Violations []PolicyViolation
+}
+
+func (e *PolicyError) Error() string {
+	lines := make([]string, 0, len(e.Violations)+1)
+	lines = append(lines, fmt.Sprintf("found %d policy violation(s):", len(e.Violations)))
+	for _, violation := range e.Violations {
+		lines = append(lines, "\t"+violation.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// policySuppression matches comments suppressing rules for a whole file,
+// e.g. "-- policy:ignore alter-column-type create-index-without-concurrently".
+var policySuppression = regexp.MustCompile(`^\s*--\s*policy:ignore\s+(.+)$`)
+
+// Patterns of statements built-in rules inspect.
+var (
+	createTablePattern = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:(?:GLOBAL\s+|LOCAL\s+)?(?:TEMPORARY|TEMP)\s+|UNLOGGED\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([\w."]+)`)
+	createIndexPattern = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?.*?\bON\s+(?:ONLY\s+)?([\w."]+)`)
+	alterTablePattern  = regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?([\w."]+)`)
+	addColumnDefault   = regexp.MustCompile(`(?is)\bADD\s+(?:COLUMN\s+)?.*\bDEFAULT\s+(.*)`)
+	volatileFunction   = regexp.MustCompile(`(?i)\b(random|clock_timestamp|timeofday|gen_random_uuid|uuid_generate_v[14]|nextval)\s*\(`)
+	alterColumnType    = regexp.MustCompile(`(?is)\bALTER\s+(?:COLUMN\s+)?[\w"]+\s+(?:SET\s+DATA\s+)?TYPE\b`)
+)
+
+// normalizeTableName lower-cases an identifier and strips its quotes.
+func normalizeTableName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, `"`, ""))
+}
+
+// AddColumnVolatileDefaultRule reports columns added to existing tables with
+// a volatile default, such as random() or gen_random_uuid(), which makes
+// PostgreSQL rewrite the whole table under an exclusive lock.
+func AddColumnVolatileDefaultRule(severity PolicySeverity) PolicyRule {
+	return PolicyRule{
+		Name:     "add-column-volatile-default",
+		Severity: severity,
+		Check: func(stmt PolicyStatement) string {
+			table := alterTablePattern.FindStringSubmatch(stmt.SQL)
+			if table == nil || stmt.createdTable(normalizeTableName(table[1])) {
+				return ""
+			}
+			def := addColumnDefault.FindStringSubmatch(stmt.SQL)
+			if def == nil {
+				return ""
+			}
+			if fn := volatileFunction.FindStringSubmatch(def[1]); fn != nil {
+				return fmt.Sprintf("adding a column with volatile default %s() rewrites table %s under an exclusive lock", strings.ToLower(fn[1]), table[1])
+			}
+			return ""
+		},
+	}
+}
+
+// CreateIndexWithoutConcurrentlyRule reports indexes created on existing
+// tables without CONCURRENTLY, which blocks writes to the table
+// while the index is built.
+func CreateIndexWithoutConcurrentlyRule(severity PolicySeverity) PolicyRule {
+	return PolicyRule{
+		Name:     "create-index-without-concurrently",
+		Severity: severity,
+		Check: func(stmt PolicyStatement) string {
+			index := createIndexPattern.FindStringSubmatch(stmt.SQL)
+			if index == nil || index[1] != "" || stmt.createdTable(normalizeTableName(index[2])) {
+				return ""
+			}
+			return fmt.Sprintf("creating an index on %s without CONCURRENTLY blocks writes", index[2])
+		},
+	}
+}
+
+// AlterColumnTypeRule reports column type changes on existing tables,
+// which usually rewrite the table under an exclusive lock.
+func AlterColumnTypeRule(severity PolicySeverity) PolicyRule {
+	return PolicyRule{
+		Name:     "alter-column-type",
+		Severity: severity,
+		Check: func(stmt PolicyStatement) string {
+			table := alterTablePattern.FindStringSubmatch(stmt.SQL)
+			if table == nil || stmt.createdTable(normalizeTableName(table[1])) {
+				return ""
+			}
+			if !alterColumnType.MatchString(stmt.SQL) {
+				return ""
+			}
+			return fmt.Sprintf("changing a column type of %s may rewrite the table under an exclusive lock", table[1])
+		},
+	}
+}
+
+// DefaultPolicyRules returns all built-in rules with the given severity.
+func DefaultPolicyRules(severity PolicySeverity) []PolicyRule {
+	return []PolicyRule{
+		AddColumnVolatileDefaultRule(severity),
+		CreateIndexWithoutConcurrentlyRule(severity),
+		AlterColumnTypeRule(severity),
+	}
+}
+
+// CheckPolicy inspects Up sections of SQL migrations with the rules set
+// with WithPolicy and returns all violations, in file and line order.
+// Go migrations are not inspected.
+//
+// A rule is suppressed for a whole file by a comment anywhere in it:
+//
+//	-- policy:ignore create-index-without-concurrently alter-column-type
+//
+// "-- policy:ignore all" suppresses all rules.
+func (r *MigrationRunner) CheckPolicy() ([]PolicyViolation, error) {
+	if len(r.policyRules) == 0 {
+		return nil, nil
+	}
+
+	sources, err := collectSources(r.migrationsFs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect migrations: %w", err)
+	}
+
+	var violations []PolicyViolation
+	for _, source := range sources {
+		if source.Type != goose.TypeSQL {
+			continue
+		}
+		found, err := r.checkPolicyFile(source.Path)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, found...)
+	}
+	return violations, nil
+}
+
+// checkPolicyFile inspects a single SQL migration file.
+func (r *MigrationRunner) checkPolicyFile(path string) ([]PolicyViolation, error) {
+	content, err := fs.ReadFile(r.migrationsFs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration %s: %w", path, err)
+	}
+	parsed, err := parseSQLMigration(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	suppressed := policySuppressions(content)
+
+	var violations []PolicyViolation
+	var createdTables []string
+	for _, stmt := range parsed.up {
+		policyStmt := PolicyStatement{
+			File:          path,
+			Line:          stmt.line,
+			SQL:           stmt.sql,
+			UseTx:         parsed.useTx,
+			CreatedTables: createdTables,
+		}
+		for _, rule := range r.policyRules {
+			if suppressed["all"] || suppressed[rule.Name] {
+				continue
+			}
+			if message := rule.Check(policyStmt); message != "" {
+				violations = append(violations, PolicyViolation{
+					Rule:     rule.Name,
+					Severity: rule.Severity,
+					File:     path,
+					Line:     stmt.line,
+					Message:  message,
+				})
+			}
+		}
+
+		if table := createTablePattern.FindStringSubmatch(stmt.sql); table != nil {
+			createdTables = append(createdTables[:len(createdTables):len(createdTables)], normalizeTableName(table[1]))
+		}
+	}
+	return violations, nil
+}
+
+// policySuppressions returns rule names suppressed by comments in content.
+func policySuppressions(content []byte) map[string]bool {
+	suppressed := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if match := policySuppression.FindStringSubmatch(scanner.Text()); match != nil {
+			for _, name := range strings.FieldsFunc(match[1], func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			}) {
+				suppressed[name] = true
+			}
+		}
+	}
+	return suppressed
+}
+
+// enforcePolicy checks migrations before a run. Warnings are recorded
+// in the report and logged, failures are returned as a *PolicyError.
+func (r *MigrationRunner) enforcePolicy(ctx context.Context, report *RunReport) error {
+	violations, err := r.CheckPolicy()
+	if err != nil {
+		return fmt.Errorf("failed to check policy: %w", err)
+	}
+
+	var failures []PolicyViolation
+	for _, violation := range violations {
+		if violation.Severity == PolicyFail {
+			failures = append(failures, violation)
+			continue
+		}
+		report.PolicyWarnings = append(report.PolicyWarnings, violation)
+		if r.logger != nil {
+			r.logger.LogAttrs(ctx, slog.LevelWarn, "migration policy violation",
+				slog.String("rule", violation.Rule),
+				slog.String("file", violation.File),
+				slog.Int("line", violation.Line),
+				slog.String("message", violation.Message),
+			)
+		}
+	}
+	if len(failures) > 0 {
+		return &PolicyError{Violations: failures}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMigrationRunnerCheckPolicy(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	migrations := fstest.MapFS{
+		"00001_create_users.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE users (id int, name text);
+CREATE INDEX users_name_idx ON users (name);
+ALTER TABLE users ADD COLUMN token uuid DEFAULT gen_random_uuid();
+
+-- +goose Down
+DROP TABLE users;
+`)},
+		"00002_alter_users.sql": {Data: []byte(`-- +goose Up
+CREATE UNIQUE INDEX IF NOT EXISTS users_token_idx
+    ON public.users (token);
+ALTER TABLE users ADD COLUMN created_at timestamptz DEFAULT now();
+ALTER TABLE users ADD COLUMN salt float8 DEFAULT random();
+ALTER TABLE ONLY "users" ALTER COLUMN name TYPE varchar(100);
+
+-- +goose Down
+ALTER TABLE users ALTER COLUMN name TYPE text;
+DROP INDEX users_token_idx;
+`)},
+		"00003_index_users.sql": {Data: []byte(`-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY users_created_at_idx ON users (created_at);
+`)},
+		"00004_suppressed.sql": {Data: []byte(`-- policy:ignore create-index-without-concurrently, alter-column-type
+-- +goose Up
+CREATE INDEX users_salt_idx ON users (salt);
+ALTER TABLE users ALTER name SET DATA TYPE text;
+ALTER TABLE users ADD COLUMN id2 int DEFAULT nextval('users_id2_seq');
+`)},
+		"00005_ignore_all.sql": {Data: []byte(`-- +goose Up
+-- policy:ignore all
+CREATE INDEX users_id2_idx ON users (id2);
+`)},
+	}
+
+	c.Run("No rules", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(migrations)
+		violations, err := runner.CheckPolicy()
+		c.Assert(err, qt.IsNil)
+		c.Assert(violations, qt.HasLen, 0)
+	})
+
+	c.Run("Built-in rules", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(migrations,
+			pgdbtemplategoose.WithPolicy(pgdbtemplategoose.DefaultPolicyRules(pgdbtemplategoose.PolicyFail)...))
+		violations, err := runner.CheckPolicy()
+		c.Assert(err, qt.IsNil)
+
+		var formatted []string
+		for _, violation := range violations {
+			c.Assert(violation.Severity, qt.Equals, pgdbtemplategoose.PolicyFail)
+			formatted = append(formatted, violation.String())
+		}
+		c.Assert(formatted, qt.DeepEquals, []string{
+			"00002_alter_users.sql:2: create-index-without-concurrently: creating an index on public.users without CONCURRENTLY blocks writes",
+			"00002_alter_users.sql:5: add-column-volatile-default: adding a column with volatile default random() rewrites table users under an exclusive lock",
+			`00002_alter_users.sql:6: alter-column-type: changing a column type of "users" may rewrite the table under an exclusive lock`,
+			"00004_suppressed.sql:5: add-column-volatile-default: adding a column with volatile default nextval() rewrites table users under an exclusive lock",
+		})
+	})
+
+	c.Run("Custom rule", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(migrations,
+			pgdbtemplategoose.WithPolicy(pgdbtemplategoose.PolicyRule{
+				Name:     "no-create-table",
+				Severity: pgdbtemplategoose.PolicyWarn,
+				Check: func(stmt pgdbtemplategoose.PolicyStatement) string {
+					if strings.HasPrefix(stmt.SQL, "CREATE TABLE") {
+						return "tables are created by the platform team"
+					}
+					return ""
+				},
+			}))
+		violations, err := runner.CheckPolicy()
+		c.Assert(err, qt.IsNil)
+		c.Assert(violations, qt.DeepEquals, []pgdbtemplategoose.PolicyViolation{{
+			Rule:     "no-create-table",
+			Severity: pgdbtemplategoose.PolicyWarn,
+			File:     "00001_create_users.sql",
+			Line:     2,
+			Message:  "tables are created by the platform team",
+		}})
+	})
+
+	c.Run("Unparsable migration", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(fstest.MapFS{
+			"00001_no_up.sql": {Data: []byte("CREATE TABLE users (id int);\n")},
+		}, pgdbtemplategoose.WithPolicy(pgdbtemplategoose.DefaultPolicyRules(pgdbtemplategoose.PolicyFail)...))
+		_, err := runner.CheckPolicy()
+		c.Assert(err, qt.ErrorMatches, "failed to parse 00001_no_up.sql: .*")
+	})
+
+	c.Run("Pre-flight check", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(migrations,
+			pgdbtemplategoose.WithPolicy(pgdbtemplategoose.AlterColumnTypeRule(pgdbtemplategoose.PolicyFail)))
+
+		// The check fails before the connection is used.
+		err := runner.RunMigrations(context.Background(), &sqlDBConnection{})
+		var policyErr *pgdbtemplategoose.PolicyError
+		c.Assert(errors.As(err, &policyErr), qt.IsTrue)
+		c.Assert(err, qt.ErrorMatches, `found 1 policy violation\(s\):\n\t00002_alter_users.sql:6: alter-column-type: .*`)
+	})
+}
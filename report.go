@@ -0,0 +1,115 @@
+package pgdbtemplategoose
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// MigrationResult describes a single migration executed by RunMigrations.
+type MigrationResult struct {
+	// Version is the migration version.
+	Version int64
+	// Type is the migration type, either goose.TypeSQL or goose.TypeGo.
+	Type goose.MigrationType
+	// Path is the migration source path in the migrations filesystem.
+	// It is empty for Go migrations registered without a file.
+	Path string
+	// Source is the name of the source the migration comes from, see
+	// WithMigrationSources. It is empty for migrations from migrationsFs.
+	Source string
+	// Schema is the schema the migration was applied into, see WithSchemas.
+	// It is empty unless per-schema runs are enabled.
+	Schema string
+	// Direction is the migration direction, "up" or "down".
+	Direction string
+	// Duration is the time it took to run the migration.
+	Duration time.Duration
+	// Empty reports whether the migration had nothing to execute
+	// (no SQL statements or a nil Go function) but was still versioned.
+	Empty bool
+	// Error is the error the migration failed with, if any.
+	Error error
+	// Stats describes locks and statements observed while the migration
+	// was running. It is nil unless WithLockStats is used and the
+	// connection pool allows sampling.
+	Stats *MigrationStats
+}
+
+// RunReport describes a single RunMigrations call.
+type RunReport struct {
+	// Migrations lists migrations in the order they were executed,
+	// including the failed one, if any.
+	Migrations []MigrationResult
+	// Reused reports whether the database was already up to date
+	// and migrations were skipped, see WithReuse.
+	Reused bool
+	// PolicyWarnings are violations of policy rules
+	// with the PolicyWarn severity, see WithPolicy.
+	PolicyWarnings []PolicyViolation
+	// Duration is the total time RunMigrations took.
+	Duration time.Duration
+	// Error is the error RunMigrations returned, if any.
+	Error error
+
+	// stats holds statistics of migrations by their goose result.
+	stats map[*goose.MigrationResult]*MigrationStats
+	// schemas holds schemas of migrations by their goose result.
+	schemas map[*goose.MigrationResult]string
+	// started holds start times of migrations by their goose result.
+	started map[*goose.MigrationResult]time.Time
+	// starts holds start times of Migrations, in the same order.
+	// A start time is zero if it was not recorded.
+	starts []time.Time
+}
+
+// recordStart records the start time of a migration.
+func (r *RunReport) recordStart(result *goose.MigrationResult, start time.Time) {
+	if r.started == nil {
+		r.started = make(map[*goose.MigrationResult]time.Time)
+	}
+	r.started[result] = start
+}
+
+// gooseResults returns results, or if there are none and err is
+// a *goose.PartialError, the applied migrations followed by the failed one.
+// The applied migrations are copied, as appending to goose's slice
+// could overwrite its backing array.
+func gooseResults(results []*goose.MigrationResult, err error) []*goose.MigrationResult {
+	var partialErr *goose.PartialError
+	if len(results) == 0 && errors.As(err, &partialErr) {
+		return append(append([]*goose.MigrationResult(nil), partialErr.Applied...), partialErr.Failed)
+	}
+	return results
+}
+
+// newMigrationResults converts goose results into MigrationResult values.
+//
+// If no results are given and err is a *goose.PartialError,
+// both the applied migrations and the failed one are taken from it.
+// Statistics, schemas and start times recorded in report are attached
+// to the results they belong to.
+func newMigrationResults(results []*goose.MigrationResult, err error, report *RunReport) []MigrationResult {
+	results = gooseResults(results, err)
+	converted := make([]MigrationResult, 0, len(results))
+	report.starts = make([]time.Time, 0, len(results))
+	for _, result := range results {
+		if result == nil || result.Source == nil {
+			continue
+		}
+		converted = append(converted, MigrationResult{
+			Version:   result.Source.Version,
+			Type:      result.Source.Type,
+			Path:      result.Source.Path,
+			Direction: result.Direction,
+			Duration:  result.Duration,
+			Empty:     result.Empty,
+			Error:     result.Error,
+			Schema:    report.schemas[result],
+			Stats:     report.stats[result],
+		})
+		report.starts = append(report.starts, report.started[result])
+	}
+	return converted
+}
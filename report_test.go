@@ -0,0 +1,119 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+	"github.com/pressly/goose/v3"
+)
+
+func TestMigrationRunnerReport(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	c.Run("Successful run", func(c *qt.C) {
+		c.Parallel()
+
+		migrationsFs := writeMigrations(c, map[string]string{
+			"00001_create_reports.sql": `-- +goose Up
+CREATE TABLE goose_report_test (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE goose_report_test;
+`,
+			"00002_empty.sql": `-- +goose Up
+
+-- +goose Down
+`,
+		})
+
+		var callbackReports []*pgdbtemplategoose.RunReport
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			migrationsFs,
+			pgdbtemplategoose.WithReportCallback(func(report *pgdbtemplategoose.RunReport) {
+				callbackReports = append(callbackReports, report)
+			}),
+		)
+		c.Assert(runner.LastReport(), qt.IsNil)
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+		defer tm.Cleanup(ctx)
+
+		report := runner.LastReport()
+		c.Assert(report, qt.IsNotNil)
+		c.Assert(report.Error, qt.IsNil)
+		c.Assert(report.Duration > 0, qt.IsTrue)
+		c.Assert(report.Migrations, qt.HasLen, 2)
+
+		first := report.Migrations[0]
+		c.Assert(first.Version, qt.Equals, int64(1))
+		c.Assert(first.Type, qt.Equals, goose.TypeSQL)
+		c.Assert(first.Path, qt.Equals, "00001_create_reports.sql")
+		c.Assert(first.Direction, qt.Equals, "up")
+		c.Assert(first.Empty, qt.IsFalse)
+		c.Assert(first.Error, qt.IsNil)
+
+		second := report.Migrations[1]
+		c.Assert(second.Version, qt.Equals, int64(2))
+		c.Assert(second.Empty, qt.IsTrue)
+
+		c.Assert(callbackReports, qt.HasLen, 1)
+		c.Assert(callbackReports[0], qt.Equals, report)
+	})
+
+	c.Run("Failed run", func(c *qt.C) {
+		c.Parallel()
+
+		migrationsFs := writeMigrations(c, map[string]string{
+			"00001_create_reports.sql": `-- +goose Up
+CREATE TABLE goose_report_failed_test (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE goose_report_failed_test;
+`,
+			"00002_invalid.sql": `-- +goose Up
+INVALID SQL SYNTAX HERE!!!
+
+-- +goose Down
+`,
+		})
+
+		var callbackReport *pgdbtemplategoose.RunReport
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			migrationsFs,
+			pgdbtemplategoose.WithReportCallback(func(report *pgdbtemplategoose.RunReport) {
+				callbackReport = report
+			}),
+		)
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.ErrorMatches, ".*failed to run goose migrations:.*")
+
+		report := runner.LastReport()
+		c.Assert(report, qt.IsNotNil)
+		c.Assert(report.Error, qt.ErrorMatches, "failed to run goose migrations:.*")
+		c.Assert(report.Migrations, qt.HasLen, 2)
+		c.Assert(report.Migrations[0].Error, qt.IsNil)
+		c.Assert(report.Migrations[1].Version, qt.Equals, int64(2))
+		c.Assert(report.Migrations[1].Error, qt.IsNotNil)
+		c.Assert(callbackReport, qt.Equals, report)
+	})
+}
@@ -0,0 +1,159 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+)
+
+// ReusePolicy controls how RunMigrations treats databases
+// already migrated by a previous run, see WithReuse.
+type ReusePolicy int
+
+const (
+	// ReuseDisabled always runs migrations as usual. This is the default.
+	ReuseDisabled ReusePolicy = iota
+	// ReuseOrFail skips migrations if the database is up to date
+	// and fails with ErrDatabaseDiverged if it is not.
+	ReuseOrFail
+	// ReuseOrRebuild skips migrations if the database is up to date.
+	// Otherwise, it rolls back all applied migrations using their Down
+	// sections and applies them again.
+	ReuseOrRebuild
+)
+
+// ErrDatabaseDiverged is returned by RunMigrations with the ReuseOrFail
+// policy when the database was migrated from different migrations.
+var ErrDatabaseDiverged = errors.New("database diverged from migrations")
+
+// fingerprintCommentPrefix prefixes the fingerprint stored
+// as the comment of the goose version table.
+const fingerprintCommentPrefix = "pgdbtemplate-goose fingerprint: "
+
+// reuseState describes a database inspected before reuse.
+type reuseState struct {
+	// fresh is true if no migrations have been applied yet.
+	fresh bool
+	// divergence explains why the database cannot be reused as is.
+	// It is empty if the database is up to date.
+	divergence string
+}
+
+// runWithReuse runs migrations according to the reuse policy.
+//
+// Databases are up to date when all migrations up to the target version are
+// applied, none beyond it are, and the fingerprint stored by the last run
+// matches the current one.
+func (r *MigrationRunner) runWithReuse(ctx context.Context, db *sql.DB, provider *goose.Provider, versionTable string, observer *runObserver) ([]*goose.MigrationResult, error) {
+	fingerprint, err := r.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := r.inspectForReuse(ctx, db, provider, versionTable, fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect database: %w", err)
+	}
+
+	var results []*goose.MigrationResult
+	switch {
+	case state.fresh:
+		// Nothing to reuse, migrate as usual.
+	case state.divergence == "":
+		observer.report.Reused = true
+		return nil, nil
+	case r.reusePolicy == ReuseOrRebuild:
+		results, err = provider.DownTo(ctx, 0)
+		if err != nil {
+			return results, fmt.Errorf("failed to roll back diverged database (%s): %w", state.divergence, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrDatabaseDiverged, state.divergence)
+	}
+
+	// Keep the rollback results next to the failed ones.
+	upResults, err := r.up(ctx, db, provider, observer)
+	results = append(results, gooseResults(upResults, err)...)
+	if err != nil {
+		return results, fmt.Errorf("failed to run goose migrations: %w", err)
+	}
+
+	if err := r.afterUp(ctx, db); err != nil {
+		return results, err
+	}
+	if err := storeFingerprint(ctx, db, versionTable, fingerprint); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// inspectForReuse compares the database with the migrations.
+func (r *MigrationRunner) inspectForReuse(ctx context.Context, db *sql.DB, provider *goose.Provider, versionTable, fingerprint string) (*reuseState, error) {
+	// Status creates the version table if it does not exist yet.
+	statuses, err := provider.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dbVersion, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if dbVersion == 0 {
+		return &reuseState{fresh: true}, nil
+	}
+
+	target := r.targetVersion
+	if target == 0 {
+		target = statuses[len(statuses)-1].Source.Version
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		if status.Source.Version <= target && status.State == goose.StatePending {
+			pending = append(pending, fmt.Sprint(status.Source.Version))
+		}
+	}
+	if len(pending) > 0 {
+		return &reuseState{divergence: fmt.Sprintf("pending migrations %s", strings.Join(pending, ", "))}, nil
+	}
+	if dbVersion > target {
+		return &reuseState{divergence: fmt.Sprintf("database version %d is beyond target version %d", dbVersion, target)}, nil
+	}
+
+	stored, err := loadFingerprint(ctx, db, versionTable)
+	if err != nil {
+		return nil, err
+	}
+	if stored != fingerprint {
+		return &reuseState{divergence: fmt.Sprintf("fingerprint %q does not match %q", stored, fingerprint)}, nil
+	}
+	return &reuseState{}, nil
+}
+
+// loadFingerprint returns the fingerprint stored in the database,
+// or an empty string if there is none.
+func loadFingerprint(ctx context.Context, db *sql.DB, versionTable string) (string, error) {
+	var comment sql.NullString
+	err := db.QueryRowContext(ctx,
+		"SELECT obj_description(to_regclass($1), 'pg_class')",
+		versionTable,
+	).Scan(&comment)
+	if err != nil {
+		return "", fmt.Errorf("failed to load fingerprint: %w", err)
+	}
+	return strings.TrimPrefix(comment.String, fingerprintCommentPrefix), nil
+}
+
+// storeFingerprint stores the fingerprint as the comment of the version table,
+// so that it is cloned together with the template.
+func storeFingerprint(ctx context.Context, db *sql.DB, versionTable, fingerprint string) error {
+	query := fmt.Sprintf("COMMENT ON TABLE %s IS '%s'", versionTable, fingerprintCommentPrefix+fingerprint)
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to store fingerprint: %w", err)
+	}
+	return nil
+}
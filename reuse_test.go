@@ -0,0 +1,253 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMigrationRunnerReuse(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	migrations := map[string]string{
+		"00001_create_items.sql": `-- +goose Up
+CREATE TABLE goose_reuse_items (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE goose_reuse_items;
+`,
+		"00002_add_name.sql": `-- +goose Up
+ALTER TABLE goose_reuse_items ADD COLUMN name TEXT;
+
+-- +goose Down
+ALTER TABLE goose_reuse_items DROP COLUMN name;
+`,
+	}
+	changed := map[string]string{
+		"00001_create_items.sql": migrations["00001_create_items.sql"],
+		"00002_add_name.sql": `-- +goose Up
+ALTER TABLE goose_reuse_items ADD COLUMN name VARCHAR(100);
+
+-- +goose Down
+ALTER TABLE goose_reuse_items DROP COLUMN name;
+`,
+	}
+
+	// migrated returns a test database cloned from a template built by runner.
+	migrated := func(c *qt.C, runner pgdbtemplate.MigrationRunner) *pgdbtemplatepq.DatabaseConnection {
+		provider := pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() {
+			testDB.Close()
+			tm.DropTestDatabase(ctx, dbName)
+		})
+		return testDB.(*pgdbtemplatepq.DatabaseConnection)
+	}
+
+	c.Run("Up to date database is reused", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrFail),
+		)
+		testDB := migrated(c, runner)
+		c.Assert(runner.LastReport().Reused, qt.IsFalse)
+		c.Assert(runner.LastReport().Migrations, qt.HasLen, 2)
+
+		// The clone carries the fingerprint of the template.
+		err := runner.RunMigrations(ctx, testDB)
+		c.Assert(err, qt.IsNil)
+		c.Assert(runner.LastReport().Reused, qt.IsTrue)
+		c.Assert(runner.LastReport().Migrations, qt.HasLen, 0)
+	})
+
+	c.Run("Diverged database fails", func(c *qt.C) {
+		c.Parallel()
+
+		testDB := migrated(c, pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrFail),
+		))
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, changed),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrFail),
+		)
+		err := runner.RunMigrations(ctx, testDB)
+		c.Assert(errors.Is(err, pgdbtemplategoose.ErrDatabaseDiverged), qt.IsTrue)
+		c.Assert(err, qt.ErrorMatches, ".*fingerprint.*does not match.*")
+	})
+
+	c.Run("Pending migrations fail", func(c *qt.C) {
+		c.Parallel()
+
+		testDB := migrated(c, pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithTargetVersion(1),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrFail),
+		))
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrFail),
+		)
+		err := runner.RunMigrations(ctx, testDB)
+		c.Assert(errors.Is(err, pgdbtemplategoose.ErrDatabaseDiverged), qt.IsTrue)
+		c.Assert(err, qt.ErrorMatches, ".*pending migrations 2.*")
+	})
+
+	c.Run("Diverged database is rebuilt", func(c *qt.C) {
+		c.Parallel()
+
+		testDB := migrated(c, pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrRebuild),
+		))
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, changed),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrRebuild),
+		)
+		err := runner.RunMigrations(ctx, testDB)
+		c.Assert(err, qt.IsNil)
+
+		report := runner.LastReport()
+		c.Assert(report.Reused, qt.IsFalse)
+		c.Assert(report.Migrations, qt.HasLen, 4)
+		c.Assert(report.Migrations[0].Direction, qt.Equals, "down")
+		c.Assert(report.Migrations[3].Direction, qt.Equals, "up")
+
+		var dataType string
+		err = testDB.DB.QueryRowContext(ctx, `
+			SELECT data_type
+			FROM information_schema.columns
+			WHERE table_schema = 'public'
+			AND table_name = 'goose_reuse_items'
+			AND column_name = 'name'
+		`).Scan(&dataType)
+		c.Assert(err, qt.IsNil)
+		c.Assert(dataType, qt.Equals, "character varying")
+
+		// The rebuilt database is now up to date.
+		err = runner.RunMigrations(ctx, testDB)
+		c.Assert(err, qt.IsNil)
+		c.Assert(runner.LastReport().Reused, qt.IsTrue)
+	})
+
+	c.Run("Per-schema databases are reused", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithSchemas("goose_reuse_a", "goose_reuse_b"),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrFail),
+		)
+		testDB := migrated(c, runner)
+		c.Assert(runner.LastReport().Migrations, qt.HasLen, 4)
+
+		// Every schema's version table carries the fingerprint.
+		err := runner.RunMigrations(ctx, testDB)
+		c.Assert(err, qt.IsNil)
+		c.Assert(runner.LastReport().Reused, qt.IsTrue)
+	})
+
+	// initialize builds the fixed-name template with runner,
+	// reusing it if it exists.
+	initialize := func(c *qt.C, templateName string, runner *pgdbtemplategoose.MigrationRunner) (*pgdbtemplategoose.TemplateManager, error) {
+		tm, err := pgdbtemplategoose.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+			MigrationRunner:    runner,
+			TemplateName:       templateName,
+		})
+		c.Assert(err, qt.IsNil)
+		return tm, tm.Initialize(ctx)
+	}
+
+	c.Run("Stale template is rebuilt", func(c *qt.C) {
+		c.Parallel()
+
+		templateName := fmt.Sprintf("goose_reuse_template_%d", time.Now().UnixNano())
+		tm, err := initialize(c, templateName, pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrRebuild),
+		))
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+
+		// The same migrations reuse the template.
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrRebuild),
+		)
+		_, err = initialize(c, templateName, runner)
+		c.Assert(err, qt.IsNil)
+		c.Assert(runner.LastReport(), qt.IsNil)
+
+		// Changed migrations rebuild it.
+		runner = pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, changed),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrRebuild),
+		)
+		rebuilt, err := initialize(c, templateName, runner)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { rebuilt.Cleanup(ctx) })
+		c.Assert(runner.LastReport().Migrations, qt.HasLen, 2)
+
+		testDB, dbName, err := rebuilt.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() {
+			testDB.Close()
+			rebuilt.DropTestDatabase(ctx, dbName)
+		})
+		var dataType string
+		err = testDB.QueryRowContext(ctx, `
+			SELECT data_type
+			FROM information_schema.columns
+			WHERE table_schema = 'public'
+			AND table_name = 'goose_reuse_items'
+			AND column_name = 'name'
+		`).Scan(&dataType)
+		c.Assert(err, qt.IsNil)
+		c.Assert(dataType, qt.Equals, "character varying")
+	})
+
+	c.Run("Stale template fails", func(c *qt.C) {
+		c.Parallel()
+
+		templateName := fmt.Sprintf("goose_reuse_template_%d", time.Now().UnixNano())
+		tm, err := initialize(c, templateName, pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrFail),
+		))
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+
+		_, err = initialize(c, templateName, pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, changed),
+			pgdbtemplategoose.WithReuse(pgdbtemplategoose.ReuseOrFail),
+		))
+		c.Assert(errors.Is(err, pgdbtemplategoose.ErrDatabaseDiverged), qt.IsTrue)
+		c.Assert(err, qt.ErrorMatches, ".*template goose_reuse_template_.*fingerprint.*does not match.*")
+	})
+}
@@ -0,0 +1,109 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+// RoundTripError is returned by RunMigrations with round-trip verification
+// enabled when a migration does not survive being rolled back and re-applied.
+type RoundTripError struct {
+	// Version is the offending migration version.
+	Version int64
+	// Err describes what went wrong.
+	Err error
+}
+
+func (e *RoundTripError) Error() string {
+	return fmt.Sprintf("round-trip verification of version %d failed: %v", e.Version, e.Err)
+}
+
+func (e *RoundTripError) Unwrap() error {
+	return e.Err
+}
+
+// upVerified applies pending migrations one by one up to the target version,
+// rolling each back and re-applying it right away. It fails if the rollback
+// or the re-application fails, or if the schema after re-applying differs
+// from the schema after the first application.
+func (r *MigrationRunner) upVerified(ctx context.Context, db *sql.DB, provider *goose.Provider, report *RunReport) (_ []*goose.MigrationResult, err error) {
+	versions, err := r.pendingVersions(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	applier, err := r.newMigrationApplier(ctx, db, provider, report)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := applier.close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release lock statistics connection: %w", closeErr))
+		}
+	}()
+
+	for _, version := range versions {
+		if err := applier.apply(ctx, version, true); err != nil {
+			return applier.results, err
+		}
+		before, err := schemaSnapshot(ctx, db, r.tableName)
+		if err != nil {
+			return applier.results, err
+		}
+
+		if err := applier.apply(ctx, version, false); err != nil {
+			return applier.results, &RoundTripError{Version: version, Err: fmt.Errorf("down migration failed: %w", err)}
+		}
+		if err := applier.apply(ctx, version, true); err != nil {
+			return applier.results, &RoundTripError{Version: version, Err: fmt.Errorf("re-applying up migration failed: %w", err)}
+		}
+
+		after, err := schemaSnapshot(ctx, db, r.tableName)
+		if err != nil {
+			return applier.results, err
+		}
+		if diff := diffSnapshots(before, after); diff != "" {
+			return applier.results, &RoundTripError{Version: version, Err: fmt.Errorf("schema after re-applying differs from first up:\n%s", diff)}
+		}
+	}
+	return applier.results, nil
+}
+
+// pendingVersions returns versions of pending migrations up to the target
+// version in ascending order. Like goose.Provider.Up, it refuses to apply
+// migrations older than the latest applied one.
+func (r *MigrationRunner) pendingVersions(ctx context.Context, provider *goose.Provider) ([]int64, error) {
+	if err := r.checkTargetVersion(provider.ListSources()); err != nil {
+		return nil, err
+	}
+
+	statuses, err := provider.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dbVersion, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions, missing []int64
+	for _, status := range statuses {
+		version := status.Source.Version
+		if status.State != goose.StatePending || (r.targetVersion > 0 && version > r.targetVersion) {
+			continue
+		}
+		if version < dbVersion {
+			missing = append(missing, version)
+			continue
+		}
+		versions = append(versions, version)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("found %d missing (out-of-order) migration(s): %v", len(missing), missing)
+	}
+	return versions, nil
+}
@@ -0,0 +1,114 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMigrationRunnerRoundTripVerification(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	createTable := `-- +goose Up
+CREATE TABLE goose_roundtrip_items (id SERIAL PRIMARY KEY);
+CREATE INDEX goose_roundtrip_items_id_idx ON goose_roundtrip_items (id);
+
+-- +goose Down
+DROP TABLE goose_roundtrip_items;
+`
+
+	// initialize builds a template with the runner.
+	initialize := func(c *qt.C, runner *pgdbtemplategoose.MigrationRunner) error {
+		provider := pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+		return tm.Initialize(ctx)
+	}
+
+	c.Run("Reversible migrations", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, map[string]string{
+				"00001_create_items.sql": createTable,
+				"00002_add_name.sql": `-- +goose Up
+ALTER TABLE goose_roundtrip_items ADD COLUMN name TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE goose_roundtrip_items DROP COLUMN name;
+`,
+			}),
+			pgdbtemplategoose.WithRoundTripVerification(),
+		)
+		err := initialize(c, runner)
+		c.Assert(err, qt.IsNil)
+
+		// Every migration went up, down and up again.
+		var directions []string
+		for _, m := range runner.LastReport().Migrations {
+			directions = append(directions, m.Direction)
+		}
+		c.Assert(directions, qt.DeepEquals, []string{"up", "down", "up", "up", "down", "up"})
+	})
+
+	c.Run("Failing down migration", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, map[string]string{
+				"00001_create_items.sql": createTable,
+				"00002_add_name.sql": `-- +goose Up
+ALTER TABLE goose_roundtrip_items ADD COLUMN name TEXT;
+
+-- +goose Down
+ALTER TABLE goose_roundtrip_items DROP COLUMN title;
+`,
+			}),
+			pgdbtemplategoose.WithRoundTripVerification(),
+		)
+		err := initialize(c, runner)
+
+		var roundTripErr *pgdbtemplategoose.RoundTripError
+		c.Assert(errors.As(err, &roundTripErr), qt.IsTrue)
+		c.Assert(roundTripErr.Version, qt.Equals, int64(2))
+		c.Assert(err, qt.ErrorMatches, "(?s).*down migration failed.*")
+	})
+
+	c.Run("Schema differs after re-applying", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, map[string]string{
+				"00001_create_items.sql": createTable,
+				// The Down section forgets to drop the index.
+				"00002_index_name.sql": `-- +goose Up
+ALTER TABLE goose_roundtrip_items ADD COLUMN name TEXT;
+CREATE INDEX IF NOT EXISTS goose_roundtrip_items_name_idx ON goose_roundtrip_items (id, name);
+
+-- +goose Down
+ALTER TABLE goose_roundtrip_items DROP COLUMN name;
+CREATE INDEX goose_roundtrip_items_legacy_idx ON goose_roundtrip_items (id);
+`,
+			}),
+			pgdbtemplategoose.WithRoundTripVerification(),
+		)
+		err := initialize(c, runner)
+
+		var roundTripErr *pgdbtemplategoose.RoundTripError
+		c.Assert(errors.As(err, &roundTripErr), qt.IsTrue)
+		c.Assert(roundTripErr.Version, qt.Equals, int64(2))
+		c.Assert(err, qt.ErrorMatches, "(?s).*schema after re-applying differs.*goose_roundtrip_items_legacy_idx.*")
+	})
+}
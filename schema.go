@@ -0,0 +1,186 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// userSchemasFilter restricts catalog queries to user-defined schemas.
+const userSchemasFilter = `NOT IN ('pg_catalog', 'information_schema')
+	AND %[1]s NOT LIKE 'pg\_toast%%'
+	AND %[1]s NOT LIKE 'pg\_temp\_%%'`
+
+// notExtensionMember excludes objects created by extensions, which are
+// described by the extension line instead. It takes the catalog of the
+// object and the expression of its oid.
+const notExtensionMember = `NOT EXISTS (
+			SELECT 1
+			FROM pg_depend x
+			WHERE x.classid = '%s'::regclass
+			AND x.objid = %s
+			AND x.deptype = 'e'
+		)`
+
+// schemaQueries describe schema objects, one line per object.
+// Queries referring to $1 take the unqualified version table name
+// to exclude it from every schema.
+var schemaQueries = []string{
+	// Columns of tables.
+	fmt.Sprintf(`SELECT format('column %%I.%%I.%%I %%s%%s%%s',
+			n.nspname, c.relname, a.attname, format_type(a.atttypid, a.atttypmod),
+			CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END,
+			COALESCE(' DEFAULT ' || pg_get_expr(d.adbin, d.adrelid), ''))
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE n.nspname %s
+		AND c.relkind IN ('r', 'p')
+		AND a.attnum > 0
+		AND NOT a.attisdropped
+		AND c.relname <> $1
+		AND %s`, fmt.Sprintf(userSchemasFilter, "n.nspname"), fmt.Sprintf(notExtensionMember, "pg_class", "c.oid")),
+	// Constraints.
+	fmt.Sprintf(`SELECT format('constraint %%s %%I %%s',
+			c.conrelid::regclass, c.conname, pg_get_constraintdef(c.oid))
+		FROM pg_constraint c
+		JOIN pg_namespace n ON n.oid = c.connamespace
+		JOIN pg_class r ON r.oid = c.conrelid
+		WHERE n.nspname %s
+		AND r.relname <> $1
+		AND %s`, fmt.Sprintf(userSchemasFilter, "n.nspname"), fmt.Sprintf(notExtensionMember, "pg_class", "r.oid")),
+	// Indexes.
+	fmt.Sprintf(`SELECT 'index ' || indexdef
+		FROM pg_indexes
+		WHERE schemaname %s
+		AND tablename <> $1
+		AND %s`, fmt.Sprintf(userSchemasFilter, "schemaname"),
+		fmt.Sprintf(notExtensionMember, "pg_class", "format('%I.%I', schemaname, tablename)::regclass")),
+	// Views.
+	fmt.Sprintf(`SELECT format('view %%I.%%I %%s', schemaname, viewname, definition)
+		FROM pg_views
+		WHERE schemaname %s
+		AND %s`, fmt.Sprintf(userSchemasFilter, "schemaname"),
+		fmt.Sprintf(notExtensionMember, "pg_class", "format('%I.%I', schemaname, viewname)::regclass")),
+	// Sequences, except those owned by a version table column.
+	fmt.Sprintf(`SELECT format('sequence %%I.%%I %%s', n.nspname, c.relname, format_type(s.seqtypid, NULL))
+		FROM pg_sequence s
+		JOIN pg_class c ON c.oid = s.seqrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname %s
+		AND NOT EXISTS (
+			SELECT 1
+			FROM pg_depend d
+			JOIN pg_class t ON t.oid = d.refobjid
+			WHERE d.classid = 'pg_class'::regclass
+			AND d.objid = c.oid
+			AND d.refclassid = 'pg_class'::regclass
+			AND d.deptype IN ('a', 'i')
+			AND t.relname = $1
+		)
+		AND %s`, fmt.Sprintf(userSchemasFilter, "n.nspname"), fmt.Sprintf(notExtensionMember, "pg_class", "c.oid")),
+	// Functions and procedures.
+	fmt.Sprintf(`SELECT 'function ' || pg_get_functiondef(p.oid)
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname %s
+		AND p.prokind IN ('f', 'p')
+		AND %s`, fmt.Sprintf(userSchemasFilter, "n.nspname"), fmt.Sprintf(notExtensionMember, "pg_proc", "p.oid")),
+	// Triggers.
+	fmt.Sprintf(`SELECT 'trigger ' || pg_get_triggerdef(t.oid)
+		FROM pg_trigger t
+		JOIN pg_class c ON c.oid = t.tgrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname %s
+		AND NOT t.tgisinternal
+		AND c.relname <> $1
+		AND %s`, fmt.Sprintf(userSchemasFilter, "n.nspname"), fmt.Sprintf(notExtensionMember, "pg_class", "c.oid")),
+	// Enum types.
+	fmt.Sprintf(`SELECT format('enum %%I.%%I %%s', n.nspname, t.typname,
+			string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder))
+		FROM pg_type t
+		JOIN pg_enum e ON e.enumtypid = t.oid
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE n.nspname %s
+		AND %s
+		GROUP BY n.nspname, t.typname`, fmt.Sprintf(userSchemasFilter, "n.nspname"), fmt.Sprintf(notExtensionMember, "pg_type", "t.oid")),
+	// Extensions.
+	`SELECT format('extension %I %s', extname, extversion)
+		FROM pg_extension`,
+}
+
+// schemaSnapshot describes the schema of a database as sorted lines,
+// one per object, so that two snapshots can be compared line by line.
+// The goose version table is not part of the snapshot.
+func schemaSnapshot(ctx context.Context, db *sql.DB, versionTable string) ([]string, error) {
+	var lines []string
+	for _, query := range schemaQueries {
+		var args []any
+		if strings.Contains(query, "$1") {
+			args = append(args, versionTable)
+		}
+		queried, err := querySnapshotLines(ctx, db, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot schema: %w", err)
+		}
+		lines = append(lines, queried...)
+	}
+	sort.Strings(lines)
+	return lines, nil
+}
+
+// querySnapshotLines returns the single text column of all rows.
+func querySnapshotLines(ctx context.Context, db *sql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, snapshotLine(line))
+	}
+	return lines, rows.Err()
+}
+
+// snapshotLine collapses whitespace, including the line breaks of function
+// bodies and view definitions, so that every object is described by a single
+// line of the golden file.
+func snapshotLine(line string) string {
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// diffSnapshots describes lines missing from and added to
+// the got snapshot compared to want, or returns an empty string
+// if the snapshots are equal.
+func diffSnapshots(want, got []string) string {
+	wantSet := make(map[string]bool, len(want))
+	for _, line := range want {
+		wantSet[line] = true
+	}
+	gotSet := make(map[string]bool, len(got))
+	for _, line := range got {
+		gotSet[line] = true
+	}
+
+	var diff strings.Builder
+	for _, line := range want {
+		if !gotSet[line] {
+			fmt.Fprintf(&diff, "- %s\n", line)
+		}
+	}
+	for _, line := range got {
+		if !wantSet[line] {
+			fmt.Fprintf(&diff, "+ %s\n", line)
+		}
+	}
+	return strings.TrimSuffix(diff.String(), "\n")
+}
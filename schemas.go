@@ -0,0 +1,213 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/pressly/goose/v3"
+)
+
+// schemaNamePattern matches schema and table names usable without
+// quoting, as goose does not quote version table names.
+var schemaNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// runSchemas applies migrations into every configured schema in turn.
+// Each schema is created if needed and migrated over a single connection
+// with search_path set to the schema alone, recording versions in the
+// schema's own version table.
+func (r *MigrationRunner) runSchemas(ctx context.Context, db *sql.DB, report *RunReport) ([]*goose.MigrationResult, error) {
+	var results []*goose.MigrationResult
+	for _, schema := range r.schemas {
+		if err := ensureSchema(ctx, db, schema); err != nil {
+			return results, err
+		}
+
+		err := withSearchPath(ctx, db, schema, func(pinned *sql.DB) error {
+			schemaResults, err := r.migrate(ctx, pinned, schema, schema+"."+r.tableName, report)
+			results = append(results, gooseResults(schemaResults, err)...)
+			return err
+		})
+		if err != nil {
+			return results, fmt.Errorf("schema %q: %w", schema, err)
+		}
+	}
+	return results, nil
+}
+
+// ensureSchema creates schema unless it exists. Like the version table
+// schema, existing schemas are not created again, as CREATE SCHEMA IF NOT
+// EXISTS requires the CREATE privilege on the database even then.
+func ensureSchema(ctx context.Context, db *sql.DB, schema string) error {
+	var exists bool
+	if err := db.QueryRowContext(ctx, tableSchemaExistsQuery, schema).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check schema %q: %w", schema, err)
+	}
+	if exists {
+		return nil
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(createTableSchemaQuery, schema)); err != nil {
+		return fmt.Errorf("failed to create schema %q: %w", schema, err)
+	}
+	return nil
+}
+
+// checkSchemas validates the configuration of per-schema runs.
+func (r *MigrationRunner) checkSchemas() error {
+	if len(r.schemas) == 0 {
+		return nil
+	}
+	if r.schemaSnapshot != nil {
+		return errors.New("schema snapshots are not supported with per-schema runs")
+	}
+	if r.lockStats != nil {
+		return errors.New("lock statistics are not supported with per-schema runs")
+	}
+	if r.tableSchema != "" {
+		return errors.New("version table schema is not supported with per-schema runs: every schema has its own version table")
+	}
+	seen := make(map[string]bool)
+	for _, schema := range r.schemas {
+		if !schemaNamePattern.MatchString(schema) {
+			return fmt.Errorf("invalid schema name %q: must be a lower-case unquoted identifier", schema)
+		}
+		if seen[schema] {
+			return fmt.Errorf("duplicate schema %q", schema)
+		}
+		seen[schema] = true
+	}
+	return nil
+}
+
+// withSearchPath calls fn with a database whose only connection is one of
+// db's, with search_path set to schema. goose takes a connection from its
+// database for every operation, so pinning a single one is the only way
+// to make a session setting stick for a whole run.
+func withSearchPath(ctx context.Context, db *sql.DB, schema string, fn func(pinned *sql.DB) error) (err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reserve connection: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, conn.Close())
+	}()
+
+	return conn.Raw(func(driverConn any) (err error) {
+		dc, ok := driverConn.(driver.Conn)
+		if !ok {
+			return fmt.Errorf("unsupported driver connection %T", driverConn)
+		}
+		pinned := sql.OpenDB(&pinnedConnector{conn: dc, driver: db.Driver()})
+		pinned.SetMaxOpenConns(1)
+		pinned.SetMaxIdleConns(1)
+		defer func() {
+			err = errors.Join(err, pinned.Close())
+		}()
+
+		// #nosec G202 -- schema names are validated identifiers.
+		if _, err := pinned.ExecContext(ctx, "SET search_path TO "+schema); err != nil {
+			return fmt.Errorf("failed to set search_path: %w", err)
+		}
+		// Leave the connection as it was before returning it to db.
+		defer func() {
+			if _, resetErr := pinned.ExecContext(context.WithoutCancel(ctx), "RESET search_path"); resetErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to reset search_path: %w", resetErr))
+			}
+		}()
+		return fn(pinned)
+	})
+}
+
+// pinnedConnector hands out a single borrowed driver connection.
+type pinnedConnector struct {
+	conn   driver.Conn
+	driver driver.Driver
+	used   bool
+}
+
+func (c *pinnedConnector) Connect(context.Context) (driver.Conn, error) {
+	// database/sql serializes calls as the database has one connection.
+	if c.used {
+		return nil, errors.New("pinned connection was discarded")
+	}
+	c.used = true
+	return pinnedConn{c.conn}, nil
+}
+
+func (c *pinnedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// pinnedConn forwards to a borrowed driver connection, except for Close:
+// the connection is returned to its own database instead.
+type pinnedConn struct {
+	conn driver.Conn
+}
+
+func (c pinnedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.conn.Prepare(query)
+}
+
+func (c pinnedConn) Close() error {
+	return nil
+}
+
+func (c pinnedConn) Begin() (driver.Tx, error) {
+	//lint:ignore SA1019 database/sql falls back to Begin for drivers without BeginTx.
+	return c.conn.Begin()
+}
+
+func (c pinnedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if conn, ok := c.conn.(driver.ConnBeginTx); ok {
+		return conn.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) || opts.ReadOnly {
+		return nil, errors.New("driver does not support transaction options")
+	}
+	return c.Begin()
+}
+
+func (c pinnedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if conn, ok := c.conn.(driver.ConnPrepareContext); ok {
+		return conn.PrepareContext(ctx, query)
+	}
+	return c.conn.Prepare(query)
+}
+
+func (c pinnedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if conn, ok := c.conn.(driver.ExecerContext); ok {
+		return conn.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c pinnedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if conn, ok := c.conn.(driver.QueryerContext); ok {
+		return conn.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c pinnedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if conn, ok := c.conn.(driver.NamedValueChecker); ok {
+		return conn.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+func (c pinnedConn) ResetSession(ctx context.Context) error {
+	if conn, ok := c.conn.(driver.SessionResetter); ok {
+		return conn.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c pinnedConn) IsValid() bool {
+	if conn, ok := c.conn.(driver.Validator); ok {
+		return conn.IsValid()
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMigrationRunnerSchemas(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	// Migrations use unqualified names, resolved through search_path.
+	migrations := fstest.MapFS{
+		"00001_create_items.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE goose_schemas_items (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE goose_schemas_items;
+`)},
+		"00002_add_name.sql": {Data: []byte(`-- +goose Up
+ALTER TABLE goose_schemas_items ADD COLUMN name TEXT;
+INSERT INTO goose_schemas_items (name) VALUES (current_schema());
+
+-- +goose Down
+ALTER TABLE goose_schemas_items DROP COLUMN name;
+`)},
+	}
+
+	c.Run("Migrations in every schema", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(migrations,
+			pgdbtemplategoose.WithSchemas("goose_tenant_a", "goose_tenant_b"))
+
+		provider := pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+		defer tm.Cleanup(ctx)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+
+		var applied [][2]string
+		for _, m := range runner.LastReport().Migrations {
+			applied = append(applied, [2]string{m.Schema, m.Path})
+		}
+		c.Assert(applied, qt.DeepEquals, [][2]string{
+			{"goose_tenant_a", "00001_create_items.sql"},
+			{"goose_tenant_a", "00002_add_name.sql"},
+			{"goose_tenant_b", "00001_create_items.sql"},
+			{"goose_tenant_b", "00002_add_name.sql"},
+		})
+
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		defer func() {
+			testDB.Close()
+			tm.DropTestDatabase(ctx, dbName)
+		}()
+
+		for _, schema := range []string{"goose_tenant_a", "goose_tenant_b"} {
+			var name string
+			err := testDB.QueryRowContext(ctx, "SELECT name FROM "+schema+".goose_schemas_items").Scan(&name)
+			c.Assert(err, qt.IsNil)
+			c.Assert(name, qt.Equals, schema)
+
+			var version int64
+			err = testDB.QueryRowContext(ctx, "SELECT max(version_id) FROM "+schema+".goose_db_version").Scan(&version)
+			c.Assert(err, qt.IsNil)
+			c.Assert(version, qt.Equals, int64(2))
+		}
+
+		// Nothing leaked into the default schema.
+		var leaked bool
+		err = testDB.QueryRowContext(ctx,
+			"SELECT to_regclass('public.goose_schemas_items') IS NOT NULL OR to_regclass('public.goose_db_version') IS NOT NULL",
+		).Scan(&leaked)
+		c.Assert(err, qt.IsNil)
+		c.Assert(leaked, qt.IsFalse)
+	})
+
+	c.Run("Invalid configuration", func(c *qt.C) {
+		c.Parallel()
+
+		for _, test := range []struct {
+			about   string
+			options []pgdbtemplategoose.Option
+			err     string
+		}{{
+			about:   "quoted schema name",
+			options: []pgdbtemplategoose.Option{pgdbtemplategoose.WithSchemas("Tenant A")},
+			err:     `invalid schema name "Tenant A": must be a lower-case unquoted identifier`,
+		}, {
+			about:   "duplicate schema",
+			options: []pgdbtemplategoose.Option{pgdbtemplategoose.WithSchemas("tenant_a", "tenant_a")},
+			err:     `duplicate schema "tenant_a"`,
+		}, {
+			about: "lock statistics",
+			options: []pgdbtemplategoose.Option{
+				pgdbtemplategoose.WithSchemas("tenant_a"),
+				pgdbtemplategoose.WithLockStats(0),
+			},
+			err: "lock statistics are not supported with per-schema runs",
+		}} {
+			// The check fails before the connection is used.
+			runner := pgdbtemplategoose.NewMigrationRunner(migrations, test.options...)
+			err := runner.RunMigrations(ctx, &sqlDBConnection{})
+			c.Assert(err, qt.ErrorMatches, test.err, qt.Commentf(test.about))
+		}
+	})
+}
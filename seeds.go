@@ -0,0 +1,92 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/andrei-polukhin/pgdbtemplate-goose/internal/fixture"
+)
+
+// collectSeedFiles returns SQL, YAML and JSON files in fsys in lexical order.
+func collectSeedFiles(fsys fs.FS) ([]string, error) {
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch path.Ext(name) {
+		case ".sql", ".yaml", ".yml", ".json":
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect seed files: %w", err)
+	}
+	return files, nil
+}
+
+// loadSeeds loads all seed files into the database in a single transaction.
+func (r *MigrationRunner) loadSeeds(ctx context.Context, db *sql.DB) (err error) {
+	if r.seedsFs == nil {
+		return nil
+	}
+
+	files, err := collectSeedFiles(r.seedsFs)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin seed transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	for _, file := range files {
+		if err = loadSeedFile(ctx, tx, r.seedsFs, file); err != nil {
+			return fmt.Errorf("failed to load seed %s: %w", file, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit seeds: %w", err)
+	}
+	return nil
+}
+
+// loadSeedFile executes a SQL seed file or inserts the rows of a fixture file.
+func loadSeedFile(ctx context.Context, tx *sql.Tx, fsys fs.FS, file string) error {
+	content, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return err
+	}
+
+	if path.Ext(file) == ".sql" {
+		_, err := tx.ExecContext(ctx, string(content))
+		return err
+	}
+
+	tables, err := fixture.Parse(content)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		for i, row := range table.Rows {
+			if _, err := tx.ExecContext(ctx, fixture.InsertQuery(table.Name, row), row.Values...); err != nil {
+				return fmt.Errorf("failed to insert row %d into %s: %w", i+1, table.Name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMigrationRunnerSeeds(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	migrations := map[string]string{
+		"00001_create_reference.sql": `-- +goose Up
+CREATE TABLE goose_seed_countries (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL
+);
+CREATE TABLE goose_seed_roles (
+    name TEXT PRIMARY KEY,
+    permissions JSONB NOT NULL DEFAULT '[]'
+);
+CREATE TABLE goose_seed_flags (
+    key TEXT PRIMARY KEY,
+    enabled BOOLEAN NOT NULL
+);
+
+-- +goose Down
+DROP TABLE goose_seed_flags;
+DROP TABLE goose_seed_roles;
+DROP TABLE goose_seed_countries;
+`,
+	}
+
+	c.Run("Seeds are cloned into test databases", func(c *qt.C) {
+		c.Parallel()
+
+		seeds := fstest.MapFS{
+			"01_countries.sql": {Data: []byte(`INSERT INTO goose_seed_countries (code, name) VALUES ('DE', 'Germany');
+INSERT INTO goose_seed_countries (code, name) VALUES ('FR', 'France');`)},
+			"02_roles.yaml": {Data: []byte(`goose_seed_roles:
+  - name: admin
+    permissions: [read, write]
+  - name: viewer
+`)},
+			"03_flags.json": {Data: []byte(`{"goose_seed_flags": [{"key": "beta", "enabled": true}]}`)},
+			"README.md":     {Data: []byte("ignored")},
+		}
+		provider := pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithSeeds(seeds),
+		)
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+		defer tm.Cleanup(ctx)
+
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		defer testDB.Close()
+		defer tm.DropTestDatabase(ctx, dbName)
+
+		pqConn := testDB.(*pgdbtemplatepq.DatabaseConnection)
+
+		var countries int
+		err = pqConn.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM goose_seed_countries").Scan(&countries)
+		c.Assert(err, qt.IsNil)
+		c.Assert(countries, qt.Equals, 2)
+
+		var permissions string
+		err = pqConn.DB.QueryRowContext(ctx, "SELECT permissions::text FROM goose_seed_roles WHERE name = 'admin'").Scan(&permissions)
+		c.Assert(err, qt.IsNil)
+		c.Assert(permissions, qt.Equals, `["read", "write"]`)
+
+		err = pqConn.DB.QueryRowContext(ctx, "SELECT permissions::text FROM goose_seed_roles WHERE name = 'viewer'").Scan(&permissions)
+		c.Assert(err, qt.IsNil)
+		c.Assert(permissions, qt.Equals, `[]`)
+
+		var enabled bool
+		err = pqConn.DB.QueryRowContext(ctx, "SELECT enabled FROM goose_seed_flags WHERE key = 'beta'").Scan(&enabled)
+		c.Assert(err, qt.IsNil)
+		c.Assert(enabled, qt.IsTrue)
+	})
+
+	c.Run("Failing seed rolls back all seeds", func(c *qt.C) {
+		c.Parallel()
+
+		seeds := fstest.MapFS{
+			"01_countries.sql": {Data: []byte(`INSERT INTO goose_seed_countries (code, name) VALUES ('DE', 'Germany');`)},
+			"02_roles.yaml":    {Data: []byte("goose_seed_roles:\n  - title: admin\n")},
+		}
+		provider := pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		runner := pgdbtemplategoose.NewMigrationRunner(writeMigrations(c, migrations))
+
+		// Migrate without seeds first to get a database to seed.
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+		defer tm.Cleanup(ctx)
+
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		defer testDB.Close()
+		defer tm.DropTestDatabase(ctx, dbName)
+
+		seeding := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithSeeds(seeds),
+		)
+		err = seeding.RunMigrations(ctx, testDB)
+		c.Assert(err, qt.ErrorMatches, `.*failed to load seed 02_roles.yaml: failed to insert row 1 into goose_seed_roles.*`)
+
+		var countries int
+		err = testDB.(*pgdbtemplatepq.DatabaseConnection).DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM goose_seed_countries").Scan(&countries)
+		c.Assert(err, qt.IsNil)
+		c.Assert(countries, qt.Equals, 0)
+	})
+}
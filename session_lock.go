@@ -0,0 +1,88 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// sessionLockConfig configures the advisory lock serializing migrations.
+type sessionLockConfig struct {
+	// timeout is the longest time to wait for the lock.
+	timeout time.Duration
+	// retryInterval is the time between attempts to take the lock.
+	retryInterval time.Duration
+}
+
+// validate checks that both durations are positive.
+func (c *sessionLockConfig) validate() error {
+	if c.timeout <= 0 {
+		return fmt.Errorf("invalid session lock timeout %s: must be greater than 0", c.timeout)
+	}
+	if c.retryInterval <= 0 {
+		return fmt.Errorf("invalid session lock retry interval %s: must be greater than 0", c.retryInterval)
+	}
+	return nil
+}
+
+// probe converts the configuration to goose's whole-second retry period
+// and number of retries after the first attempt, rounding both up.
+func (c *sessionLockConfig) probe() (period, retries uint64, err error) {
+	if err := c.validate(); err != nil {
+		return 0, 0, err
+	}
+
+	period = uint64((c.retryInterval + time.Second - 1) / time.Second)
+	interval := time.Duration(period) * time.Second
+	retries = uint64((c.timeout + interval - 1) / interval)
+	return period, retries, nil
+}
+
+// sessionLocker returns goose's Postgres session locker
+// configured with the lock timeout.
+func (c *sessionLockConfig) sessionLocker() (lock.SessionLocker, error) {
+	period, retries, err := c.probe()
+	if err != nil {
+		return nil, err
+	}
+	return lock.NewPostgresSessionLocker(lock.WithLockTimeout(period, retries))
+}
+
+// lockNative takes the same advisory lock as goose's session locker
+// on the connection and returns the function releasing it.
+func (c *sessionLockConfig) lockNative(ctx context.Context, conn *pgx.Conn) (func(context.Context) error, error) {
+	period, retries, err := c.probe()
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := uint64(0); ; attempt++ {
+		var locked bool
+		if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", lock.DefaultLockID).Scan(&locked); err != nil {
+			return nil, fmt.Errorf("failed to take session lock: %w", err)
+		}
+		if locked {
+			break
+		}
+		if attempt == retries {
+			return nil, errors.New("failed to take session lock: timed out")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to take session lock: %w", ctx.Err())
+		case <-time.After(time.Duration(period) * time.Second):
+		}
+	}
+
+	return func(ctx context.Context) error {
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", lock.DefaultLockID); err != nil {
+			return fmt.Errorf("failed to release session lock: %w", err)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,33 @@
+package pgdbtemplategoose
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSessionLockConfigProbe(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	for _, test := range []struct {
+		timeout, retryInterval time.Duration
+		period, retries        uint64
+	}{
+		{timeout: time.Minute, retryInterval: time.Second, period: 1, retries: 60},
+		{timeout: time.Minute, retryInterval: 5 * time.Second, period: 5, retries: 12},
+		{timeout: 10 * time.Second, retryInterval: 3 * time.Second, period: 3, retries: 4},
+		{timeout: 100 * time.Millisecond, retryInterval: 100 * time.Millisecond, period: 1, retries: 1},
+		{timeout: time.Second, retryInterval: 5 * time.Second, period: 5, retries: 1},
+	} {
+		config := &sessionLockConfig{timeout: test.timeout, retryInterval: test.retryInterval}
+		period, retries, err := config.probe()
+		c.Assert(err, qt.IsNil)
+		c.Assert([]uint64{period, retries}, qt.DeepEquals, []uint64{test.period, test.retries},
+			qt.Commentf("timeout %s, retry interval %s", test.timeout, test.retryInterval))
+	}
+
+	_, _, err := (&sessionLockConfig{timeout: time.Second, retryInterval: -time.Second}).probe()
+	c.Assert(err, qt.ErrorMatches, "invalid session lock retry interval -1s: must be greater than 0")
+}
@@ -0,0 +1,122 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepgx "github.com/andrei-polukhin/pgdbtemplate-pgx"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+	"github.com/pressly/goose/v3/lock"
+)
+
+func TestMigrationRunnerSessionLock(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	migrations := map[string]string{
+		"00001_create_jobs.sql": `-- +goose Up
+CREATE TABLE goose_lock_jobs (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE goose_lock_jobs;
+`,
+	}
+
+	// createTestDatabase returns an empty test database and its name.
+	createTestDatabase := func(c *qt.C, provider pgdbtemplate.ConnectionProvider) (pgdbtemplate.DatabaseConnection, string) {
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    &pgdbtemplate.NoOpMigrationRunner{},
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() {
+			testDB.Close()
+			tm.DropTestDatabase(ctx, dbName)
+		})
+		return testDB, dbName
+	}
+
+	// holdLock takes goose's advisory lock on the database from another
+	// session and returns the function releasing it.
+	holdLock := func(c *qt.C, dbName string) func() {
+		db, err := sql.Open("postgres", testConnectionStringFunc(dbName))
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { db.Close() })
+
+		conn, err := db.Conn(ctx)
+		c.Assert(err, qt.IsNil)
+		_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lock.DefaultLockID)
+		c.Assert(err, qt.IsNil)
+
+		return func() {
+			_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lock.DefaultLockID)
+			c.Assert(err, qt.IsNil)
+			c.Assert(conn.Close(), qt.IsNil)
+		}
+	}
+
+	for _, test := range []struct {
+		name     string
+		provider func(c *qt.C) pgdbtemplate.ConnectionProvider
+		options  []pgdbtemplategoose.Option
+	}{{
+		name: "goose",
+		provider: func(c *qt.C) pgdbtemplate.ConnectionProvider {
+			return pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		},
+	}, {
+		name: "pgx-native",
+		provider: func(c *qt.C) pgdbtemplate.ConnectionProvider {
+			provider := pgdbtemplatepgx.NewConnectionProvider(testConnectionStringFunc)
+			c.Cleanup(provider.Close)
+			return provider
+		},
+		options: []pgdbtemplategoose.Option{pgdbtemplategoose.WithPgxNative()},
+	}} {
+		test := test
+		c.Run(test.name, func(c *qt.C) {
+			c.Parallel()
+
+			testDB, dbName := createTestDatabase(c, test.provider(c))
+			runner := pgdbtemplategoose.NewMigrationRunner(
+				writeMigrations(c, migrations),
+				append(test.options, pgdbtemplategoose.WithSessionLock(time.Second, time.Second))...,
+			)
+
+			// Another process holds the lock.
+			unlock := holdLock(c, dbName)
+			err := runner.RunMigrations(ctx, testDB)
+			c.Assert(err, qt.ErrorMatches, ".*failed to (acquire|take session) lock.*")
+
+			// Migrations proceed once the lock is released.
+			unlock()
+			err = runner.RunMigrations(ctx, testDB)
+			c.Assert(err, qt.IsNil)
+			c.Assert(runner.LastReport().Migrations, qt.HasLen, 1)
+		})
+	}
+
+	c.Run("Invalid timeout", func(c *qt.C) {
+		c.Parallel()
+
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithSessionLock(0, time.Second),
+		)
+		err := runner.RunMigrations(ctx, &sqlDBConnection{db: &sql.DB{}})
+		c.Assert(err, qt.ErrorMatches, ".*invalid session lock timeout 0s: must be greater than 0")
+	})
+}
@@ -0,0 +1,83 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrSchemaMismatch is returned by RunMigrations when the migrated schema
+// differs from the golden file set with WithSchemaSnapshot.
+var ErrSchemaMismatch = errors.New("schema differs from snapshot")
+
+// schemaSnapshotConfig is the golden file configuration of the runner.
+type schemaSnapshotConfig struct {
+	// fsys and name locate the golden file to compare with.
+	fsys fs.FS
+	name string
+	// updatePath is the file the snapshot is written to instead,
+	// if not empty.
+	updatePath string
+}
+
+// checkSchemaSnapshot compares the schema of the migrated database with the
+// golden file, or regenerates the golden file in update mode.
+func (r *MigrationRunner) checkSchemaSnapshot(ctx context.Context, db *sql.DB) error {
+	config := r.schemaSnapshot
+	if config == nil {
+		return nil
+	}
+
+	lines, err := schemaSnapshot(ctx, db, r.tableName)
+	if err != nil {
+		return err
+	}
+	if config.updatePath != "" {
+		return writeSchemaSnapshot(config.updatePath, lines)
+	}
+
+	golden, err := readSchemaSnapshot(config.fsys, config.name)
+	if err != nil {
+		return err
+	}
+	if diff := diffSnapshots(golden, lines); diff != "" {
+		return fmt.Errorf("%w %s (- snapshot, + database):\n%s", ErrSchemaMismatch, config.name, diff)
+	}
+	return nil
+}
+
+// readSchemaSnapshot reads a golden file, ignoring empty lines.
+func readSchemaSnapshot(fsys fs.FS, name string) ([]string, error) {
+	if fsys == nil {
+		return nil, errors.New("schema snapshot file system is nil")
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema snapshot: %w", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = snapshotLine(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// writeSchemaSnapshot writes a golden file, creating its directory if needed.
+func writeSchemaSnapshot(path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to write schema snapshot: %w", err)
+	}
+	data := strings.Join(lines, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		return fmt.Errorf("failed to write schema snapshot: %w", err)
+	}
+	return nil
+}
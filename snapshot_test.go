@@ -0,0 +1,140 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMigrationRunnerSchemaSnapshot(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	migrations := map[string]string{
+		"00001_create_products.sql": `-- +goose Up
+CREATE TYPE goose_snapshot_status AS ENUM ('draft', 'published');
+CREATE TABLE goose_snapshot_products (
+    id SERIAL PRIMARY KEY,
+    sku VARCHAR(32) NOT NULL UNIQUE,
+    status goose_snapshot_status NOT NULL DEFAULT 'draft'
+);
+CREATE INDEX goose_snapshot_products_status_idx ON goose_snapshot_products (status);
+CREATE VIEW goose_snapshot_published AS
+    SELECT id, sku
+    FROM goose_snapshot_products
+    WHERE status = 'published';
+
+-- +goose StatementBegin
+CREATE FUNCTION goose_snapshot_count() RETURNS bigint AS $$
+BEGIN
+    RETURN (SELECT COUNT(*) FROM goose_snapshot_products);
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Objects of extensions are described by the extension alone.
+CREATE EXTENSION IF NOT EXISTS citext;
+
+-- +goose Down
+DROP EXTENSION citext;
+DROP FUNCTION goose_snapshot_count();
+DROP VIEW goose_snapshot_published;
+DROP TABLE goose_snapshot_products;
+DROP TYPE goose_snapshot_status;
+`,
+	}
+
+	// initialize builds a template with the runner.
+	initialize := func(c *qt.C, runner *pgdbtemplategoose.MigrationRunner) error {
+		provider := pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+		return tm.Initialize(ctx)
+	}
+
+	// update writes a fresh golden file and returns its directory.
+	update := func(c *qt.C) string {
+		dir := c.TempDir()
+		err := initialize(c, pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithSchemaSnapshotUpdate(filepath.Join(dir, "schema.golden")),
+		))
+		c.Assert(err, qt.IsNil)
+		return dir
+	}
+
+	c.Run("Update and match", func(c *qt.C) {
+		c.Parallel()
+
+		dir := update(c)
+		golden, err := os.ReadFile(filepath.Join(dir, "schema.golden"))
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(golden), qt.Contains, `column public.goose_snapshot_products.sku character varying(32) NOT NULL`)
+		c.Assert(string(golden), qt.Contains, `enum public.goose_snapshot_status 'draft', 'published'`)
+		c.Assert(string(golden), qt.Contains, `index CREATE INDEX goose_snapshot_products_status_idx`)
+		c.Assert(string(golden), qt.Not(qt.Contains), "goose_db_version")
+
+		// Function bodies and view definitions fit on a single line each.
+		lines := strings.Split(string(golden), "\n")
+		c.Assert(lines, qt.Contains, "function CREATE OR REPLACE FUNCTION public.goose_snapshot_count() "+
+			"RETURNS bigint LANGUAGE plpgsql AS $function$ BEGIN "+
+			"RETURN (SELECT COUNT(*) FROM goose_snapshot_products); END; $function$")
+		var viewLine string
+		for _, line := range lines {
+			if strings.HasPrefix(line, "view public.goose_snapshot_published ") {
+				viewLine = line
+			}
+		}
+		c.Assert(viewLine, qt.Matches, `view public.goose_snapshot_published SELECT .*goose_snapshot_products.*published.*`)
+
+		// Extension objects are not listed one by one.
+		c.Assert(string(golden), qt.Contains, "extension citext ")
+		c.Assert(string(golden), qt.Not(qt.Contains), "public.citext")
+
+		err = initialize(c, pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithSchemaSnapshot(os.DirFS(dir), "schema.golden"),
+		))
+		c.Assert(err, qt.IsNil)
+	})
+
+	c.Run("Mismatch", func(c *qt.C) {
+		c.Parallel()
+
+		dir := update(c)
+		changed := map[string]string{
+			"00001_create_products.sql": strings.Replace(migrations["00001_create_products.sql"], "VARCHAR(32)", "VARCHAR(64)", 1),
+		}
+
+		err := initialize(c, pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, changed),
+			pgdbtemplategoose.WithSchemaSnapshot(os.DirFS(dir), "schema.golden"),
+		))
+		c.Assert(errors.Is(err, pgdbtemplategoose.ErrSchemaMismatch), qt.IsTrue)
+		c.Assert(err, qt.ErrorMatches, `(?s).*- column public.goose_snapshot_products.sku character varying\(32\) NOT NULL\n\+ column public.goose_snapshot_products.sku character varying\(64\) NOT NULL.*`)
+	})
+
+	c.Run("Missing golden file", func(c *qt.C) {
+		c.Parallel()
+
+		err := initialize(c, pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, migrations),
+			pgdbtemplategoose.WithSchemaSnapshot(os.DirFS(c.TempDir()), "schema.golden"),
+		))
+		c.Assert(err, qt.ErrorMatches, ".*failed to read schema snapshot.*")
+	})
+}
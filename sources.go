@@ -0,0 +1,78 @@
+package pgdbtemplategoose
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+)
+
+// collectSources returns migration sources found in fsys,
+// sorted by version in ascending order.
+//
+// It follows goose's rules: files named NUMBER_description.sql or .go
+// are migrations, other files (and Go test files) are ignored,
+// and versions must be unique.
+func collectSources(fsys fs.FS) ([]goose.Source, error) {
+	sources, err := listSources(fsys)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(sources); i++ {
+		if sources[i].Version == sources[i-1].Version {
+			return nil, fmt.Errorf("found duplicate migration version %d: %s and %s",
+				sources[i].Version, sources[i-1].Path, sources[i].Path)
+		}
+	}
+	return sources, nil
+}
+
+// listSources returns migration sources found in fsys, sorted by version
+// and then by path, without checking versions for uniqueness.
+func listSources(fsys fs.FS) ([]goose.Source, error) {
+	if fsys == nil {
+		return nil, nil
+	}
+	if err := checkMigrationSources(fsys); err != nil {
+		return nil, err
+	}
+
+	var sources []goose.Source
+	for _, pattern := range []string{"*.sql", "*.go"} {
+		files, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to glob pattern %q: %w", pattern, err)
+		}
+		for _, file := range files {
+			if strings.HasSuffix(file, "_test.go") {
+				continue
+			}
+			version, err := goose.NumericComponent(file)
+			if err != nil {
+				// Not a migration, e.g. a helpers.go file.
+				continue
+			}
+
+			migrationType := goose.TypeSQL
+			if path.Ext(file) == ".go" {
+				migrationType = goose.TypeGo
+			}
+			sources = append(sources, goose.Source{
+				Type:    migrationType,
+				Path:    file,
+				Version: version,
+			})
+		}
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Version != sources[j].Version {
+			return sources[i].Version < sources[j].Version
+		}
+		return sources[i].Path < sources[j].Path
+	})
+	return sources, nil
+}
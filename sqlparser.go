@@ -0,0 +1,283 @@
+package pgdbtemplategoose
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/mfridman/interpolate"
+)
+
+// sqlMigration is a parsed goose SQL migration file.
+type sqlMigration struct {
+	// useTx is false if the file is annotated with "-- +goose NO TRANSACTION".
+	useTx bool
+	// up and down are statements of the respective sections.
+	up, down []sqlStatement
+}
+
+// sqlStatement is a single statement of a SQL migration.
+type sqlStatement struct {
+	// sql is the statement text, including comments inside it.
+	sql string
+	// line is the 1-based line the statement starts at.
+	line int
+}
+
+// sqlParseError is a SQL migration parsing error at a specific line.
+type sqlParseError struct {
+	line int
+	err  error
+}
+
+func (e *sqlParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+func (e *sqlParseError) Unwrap() error {
+	return e.err
+}
+
+// Goose annotations, all in the form "-- +goose <annotation>".
+const (
+	annotationUp             = "Up"
+	annotationDown           = "Down"
+	annotationStatementBegin = "StatementBegin"
+	annotationStatementEnd   = "StatementEnd"
+	annotationNoTransaction  = "NO TRANSACTION"
+	annotationEnvsubOn       = "ENVSUB ON"
+	annotationEnvsubOff      = "ENVSUB OFF"
+)
+
+var supportedAnnotations = []string{
+	annotationUp,
+	annotationDown,
+	annotationStatementBegin,
+	annotationStatementEnd,
+	annotationNoTransaction,
+	annotationEnvsubOn,
+	annotationEnvsubOff,
+}
+
+// sqlParserState is the state of the SQL migration parser.
+type sqlParserState int
+
+const (
+	stateStart sqlParserState = iota
+	stateUp
+	stateStatementUp
+	stateDown
+	stateStatementDown
+)
+
+// maxLineSize is the longest line the parser accepts, matching goose.
+const maxLineSize = 4 * 1024 * 1024
+
+// parseSQLMigrationFile parses a goose SQL migration file from fsys.
+func parseSQLMigrationFile(fsys fs.FS, path string) (*sqlMigration, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	parsed, err := parseSQLMigration(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return parsed, nil
+}
+
+// parseSQLMigration parses a goose SQL migration in a single pass.
+//
+// It follows goose's own parser: statements end with a line ending
+// in a semicolon, unless wrapped in StatementBegin/StatementEnd annotations,
+// comments before a statement are dropped, and ENVSUB sections
+// are expanded from the environment.
+func parseSQLMigration(r io.Reader) (*sqlMigration, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	parsed := &sqlMigration{useTx: true}
+	state := stateStart
+	useEnvsub := false
+
+	var buf strings.Builder
+	bufLine := 0
+	lineNum := 0
+
+	// flush stores the buffered statement in the current section.
+	flush := func() {
+		stmt := sqlStatement{sql: strings.TrimSpace(buf.String()), line: bufLine}
+		if state == stateUp || state == stateStatementUp {
+			parsed.up = append(parsed.up, stmt)
+		} else {
+			parsed.down = append(parsed.down, stmt)
+		}
+		buf.Reset()
+	}
+	fail := func(format string, args ...any) error {
+		return &sqlParseError{line: lineNum, err: fmt.Errorf(format, args...)}
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNum++
+
+		if state == stateStart && strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if isAnnotation(line) {
+			annotation, err := extractAnnotation(line)
+			if err != nil {
+				return nil, &sqlParseError{line: lineNum, err: err}
+			}
+
+			switch annotation {
+			case annotationUp:
+				if state != stateStart {
+					return nil, fail("duplicate '-- +goose Up' annotation")
+				}
+				state = stateUp
+
+			case annotationDown:
+				if state != stateUp {
+					return nil, fail("'-- +goose Down' must follow '-- +goose Up' outside of a statement block")
+				}
+				if remaining := strings.TrimSpace(buf.String()); remaining != "" {
+					return nil, &sqlParseError{line: bufLine, err: missingSemicolonError(remaining)}
+				}
+				state = stateDown
+
+			case annotationStatementBegin:
+				switch state {
+				case stateUp:
+					state = stateStatementUp
+				case stateDown:
+					state = stateStatementDown
+				default:
+					return nil, fail("'-- +goose StatementBegin' must follow '-- +goose Up' or '-- +goose Down' outside of a statement block")
+				}
+
+			case annotationStatementEnd:
+				switch state {
+				case stateStatementUp:
+					flush()
+					state = stateUp
+				case stateStatementDown:
+					flush()
+					state = stateDown
+				default:
+					return nil, fail("'-- +goose StatementEnd' must follow '-- +goose StatementBegin'")
+				}
+
+			case annotationNoTransaction:
+				parsed.useTx = false
+
+			case annotationEnvsubOn:
+				useEnvsub = true
+
+			case annotationEnvsubOff:
+				useEnvsub = false
+			}
+			continue
+		}
+
+		// Comments and empty lines before a statement are ignored.
+		if buf.Len() == 0 && (strings.HasPrefix(strings.TrimSpace(line), "--") || line == "") {
+			continue
+		}
+		if state == stateStart {
+			return nil, fail("must start with '-- +goose Up' annotation")
+		}
+
+		if useEnvsub {
+			expanded, err := interpolate.Interpolate(envLookup{}, line)
+			if err != nil {
+				return nil, fail("variable substitution failed: %w", err)
+			}
+			line = expanded
+		}
+		if buf.Len() == 0 {
+			bufLine = lineNum
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+
+		if (state == stateUp || state == stateDown) && endsWithSemicolon(line) {
+			flush()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan migration: %w", err)
+	}
+
+	switch state {
+	case stateStart:
+		return nil, errors.New("must start with '-- +goose Up' annotation")
+	case stateStatementUp, stateStatementDown:
+		return nil, fail("missing '-- +goose StatementEnd' annotation")
+	}
+	if remaining := strings.TrimSpace(buf.String()); remaining != "" {
+		return nil, &sqlParseError{line: bufLine, err: missingSemicolonError(remaining)}
+	}
+	return parsed, nil
+}
+
+// isAnnotation reports whether the line is a goose annotation.
+func isAnnotation(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "--") && strings.Contains(line, "+goose")
+}
+
+// extractAnnotation returns the annotation of a "-- +goose <annotation>" line.
+func extractAnnotation(line string) (string, error) {
+	if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+		return "", fmt.Errorf("annotation %q must not have leading whitespace", line)
+	}
+
+	cmd := strings.ReplaceAll(line, "--", "")
+	cmd = strings.Replace(cmd, "+goose", "", 1)
+	if strings.Contains(cmd, "+goose") {
+		return "", fmt.Errorf("annotation %q contains multiple '+goose' annotations", line)
+	}
+	cmd = strings.TrimSpace(cmd)
+	if cmd == "" {
+		return "", fmt.Errorf("annotation %q is empty", line)
+	}
+
+	for _, annotation := range supportedAnnotations {
+		if strings.EqualFold(annotation, cmd) {
+			return annotation, nil
+		}
+	}
+	return "", fmt.Errorf("annotation %q is not supported", line)
+}
+
+// endsWithSemicolon reports whether the line ends a statement,
+// ignoring a trailing "--" comment.
+func endsWithSemicolon(line string) bool {
+	prev := ""
+	for _, word := range strings.Fields(line) {
+		if strings.HasPrefix(word, "--") {
+			break
+		}
+		prev = word
+	}
+	return strings.HasSuffix(prev, ";")
+}
+
+func missingSemicolonError(statement string) error {
+	return fmt.Errorf("unfinished SQL statement %q: missing semicolon?", statement)
+}
+
+// envLookup resolves ENVSUB variables from the process environment.
+type envLookup struct{}
+
+func (envLookup) Get(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
@@ -0,0 +1,152 @@
+package pgdbtemplategoose
+
+import (
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParseSQLMigration(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	c.Run("Simple statements", func(c *qt.C) {
+		c.Parallel()
+
+		parsed, err := parseSQLMigration(strings.NewReader(`-- +goose Up
+-- Leading comments are dropped.
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY
+);
+INSERT INTO users DEFAULT VALUES; -- trailing comment
+
+-- +goose Down
+DROP TABLE users;
+`))
+		c.Assert(err, qt.IsNil)
+		c.Assert(parsed.useTx, qt.IsTrue)
+		c.Assert(parsed.up, qt.HasLen, 2)
+		c.Assert(parsed.up[0], qt.Equals, sqlStatement{sql: "CREATE TABLE users (\n    id SERIAL PRIMARY KEY\n);", line: 3})
+		c.Assert(parsed.up[1], qt.Equals, sqlStatement{sql: "INSERT INTO users DEFAULT VALUES; -- trailing comment", line: 6})
+		c.Assert(parsed.down, qt.HasLen, 1)
+		c.Assert(parsed.down[0], qt.Equals, sqlStatement{sql: "DROP TABLE users;", line: 9})
+	})
+
+	c.Run("Statement blocks", func(c *qt.C) {
+		c.Parallel()
+
+		parsed, err := parseSQLMigration(strings.NewReader(`-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION one() RETURNS integer AS $$
+BEGIN
+    RETURN 1;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION one();
+`))
+		c.Assert(err, qt.IsNil)
+		c.Assert(parsed.up, qt.HasLen, 1)
+		c.Assert(parsed.up[0].line, qt.Equals, 3)
+		c.Assert(parsed.up[0].sql, qt.Equals, `CREATE FUNCTION one() RETURNS integer AS $$
+BEGIN
+    RETURN 1;
+END;
+$$ LANGUAGE plpgsql;`)
+		c.Assert(parsed.down, qt.HasLen, 1)
+	})
+
+	c.Run("No transaction", func(c *qt.C) {
+		c.Parallel()
+
+		parsed, err := parseSQLMigration(strings.NewReader(`-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY users_id_idx ON users (id);
+`))
+		c.Assert(err, qt.IsNil)
+		c.Assert(parsed.useTx, qt.IsFalse)
+		c.Assert(parsed.up, qt.HasLen, 1)
+		c.Assert(parsed.down, qt.HasLen, 0)
+	})
+
+	c.Run("Errors", func(c *qt.C) {
+		c.Parallel()
+
+		tests := []struct {
+			name    string
+			input   string
+			wantErr string
+		}{{
+			name:    "missing up annotation",
+			input:   "CREATE TABLE users (id int);\n",
+			wantErr: "line 1: must start with '-- \\+goose Up' annotation",
+		}, {
+			name:    "empty file",
+			input:   "\n\n",
+			wantErr: "must start with '-- \\+goose Up' annotation",
+		}, {
+			name:    "duplicate up annotation",
+			input:   "-- +goose Up\n-- +goose Up\n",
+			wantErr: "line 2: duplicate '-- \\+goose Up' annotation",
+		}, {
+			name:    "missing semicolon before down",
+			input:   "-- +goose Up\nCREATE TABLE users (id int)\n-- +goose Down\n",
+			wantErr: "line 2: unfinished SQL statement .*: missing semicolon\\?",
+		}, {
+			name:    "missing semicolon at end",
+			input:   "-- +goose Up\nSELECT 1;\nSELECT 2\n",
+			wantErr: "line 3: unfinished SQL statement .*",
+		}, {
+			name:    "missing statement end",
+			input:   "-- +goose Up\n-- +goose StatementBegin\nSELECT 1;\n",
+			wantErr: "line 3: missing '-- \\+goose StatementEnd' annotation",
+		}, {
+			name:    "unexpected statement end",
+			input:   "-- +goose Up\n-- +goose StatementEnd\n",
+			wantErr: "line 2: '-- \\+goose StatementEnd' must follow '-- \\+goose StatementBegin'",
+		}, {
+			name:    "unknown annotation",
+			input:   "-- +goose Up\n-- +goose Sideways\n",
+			wantErr: "line 2: annotation .* is not supported",
+		}, {
+			name:    "indented annotation",
+			input:   "  -- +goose Up\n",
+			wantErr: "line 1: annotation .* must not have leading whitespace",
+		}}
+
+		for _, test := range tests {
+			_, err := parseSQLMigration(strings.NewReader(test.input))
+			c.Assert(err, qt.ErrorMatches, test.wantErr, qt.Commentf(test.name))
+		}
+	})
+}
+
+func TestParseSQLMigrationEnvsub(t *testing.T) {
+	// Not parallel: the test sets environment variables.
+	c := qt.New(t)
+	c.Setenv("PGDBTEMPLATE_GOOSE_TEST_ROLE", "reader")
+
+	parsed, err := parseSQLMigration(strings.NewReader(`-- +goose Up
+-- +goose ENVSUB ON
+CREATE ROLE ${PGDBTEMPLATE_GOOSE_TEST_ROLE};
+-- +goose ENVSUB OFF
+SELECT '${NOT_EXPANDED}';
+`))
+	c.Assert(err, qt.IsNil)
+	c.Assert(parsed.up[0].sql, qt.Equals, "CREATE ROLE reader;")
+	c.Assert(parsed.up[1].sql, qt.Equals, "SELECT '${NOT_EXPANDED}';")
+}
+
+func TestEndsWithSemicolon(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	c.Assert(endsWithSemicolon("SELECT 1;"), qt.IsTrue)
+	c.Assert(endsWithSemicolon("SELECT 1; -- comment"), qt.IsTrue)
+	c.Assert(endsWithSemicolon("SELECT 1 -- comment;"), qt.IsFalse)
+	c.Assert(endsWithSemicolon("SELECT 1"), qt.IsFalse)
+	c.Assert(endsWithSemicolon(""), qt.IsFalse)
+}
@@ -0,0 +1,148 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the OpenTelemetry instrumentation scope name.
+const instrumentationName = "github.com/andrei-polukhin/pgdbtemplate-goose"
+
+// telemetry holds OpenTelemetry instruments of the runner.
+type telemetry struct {
+	tracer trace.Tracer
+
+	duration metric.Float64Histogram
+	applied  metric.Int64Counter
+	failed   metric.Int64Counter
+}
+
+// newTelemetry creates instruments from the providers. Either may be nil.
+//
+// Failing to create instruments must not break migrations,
+// so errors are passed to the global OpenTelemetry error handler
+// and metrics are disabled.
+func newTelemetry(tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider) *telemetry {
+	t := &telemetry{}
+	if tracerProvider != nil {
+		t.tracer = tracerProvider.Tracer(instrumentationName)
+	}
+	if meterProvider == nil {
+		return t
+	}
+
+	meter := meterProvider.Meter(instrumentationName)
+	duration, durationErr := meter.Float64Histogram(
+		"pgdbtemplate.goose.migration.duration",
+		metric.WithDescription("Duration of individual migrations."),
+		metric.WithUnit("s"),
+	)
+	applied, appliedErr := meter.Int64Counter(
+		"pgdbtemplate.goose.migrations.applied",
+		metric.WithDescription("Number of successfully applied migrations."),
+		metric.WithUnit("{migration}"),
+	)
+	failed, failedErr := meter.Int64Counter(
+		"pgdbtemplate.goose.migrations.failed",
+		metric.WithDescription("Number of failed migrations."),
+		metric.WithUnit("{migration}"),
+	)
+	if err := errors.Join(durationErr, appliedErr, failedErr); err != nil {
+		otel.Handle(err)
+		return t
+	}
+	t.duration, t.applied, t.failed = duration, applied, failed
+	return t
+}
+
+// tracing reports whether migration spans are recorded.
+func (t *telemetry) tracing() bool {
+	return t != nil && t.tracer != nil
+}
+
+// startRun starts the span of a RunMigrations call.
+// The returned context carries the span, so that spans of instrumented
+// database drivers become its children.
+func (t *telemetry) startRun(ctx context.Context, r *MigrationRunner) (context.Context, trace.Span) {
+	if t == nil || t.tracer == nil {
+		return ctx, nil
+	}
+	return t.tracer.Start(ctx, "pgdbtemplategoose.RunMigrations",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("pgdbtemplate.goose.dialect", string(r.dialect)),
+			attribute.Int64("pgdbtemplate.goose.target_version", r.targetVersion),
+		),
+	)
+}
+
+// finishRun records migrations of the report and ends the run span.
+//
+// Migration spans start when their migrations actually started: goose
+// reports each migration as it completes, so its start is known from its
+// duration. Should a start time be missing, the span is assumed to have
+// ended at end.
+func (t *telemetry) finishRun(ctx context.Context, span trace.Span, report *RunReport, end time.Time) {
+	if t == nil {
+		return
+	}
+
+	for i, m := range report.Migrations {
+		attrs := []attribute.KeyValue{
+			attribute.Int64("pgdbtemplate.goose.version", m.Version),
+			attribute.String("pgdbtemplate.goose.direction", m.Direction),
+			attribute.String("pgdbtemplate.goose.type", string(m.Type)),
+		}
+
+		if span != nil {
+			start := report.starts[i]
+			if start.IsZero() {
+				start = end.Add(-m.Duration)
+			}
+			_, child := t.tracer.Start(ctx, "pgdbtemplategoose.migration",
+				trace.WithTimestamp(start),
+				trace.WithAttributes(append(attrs,
+					attribute.String("pgdbtemplate.goose.file", m.Path),
+					attribute.Bool("pgdbtemplate.goose.empty", m.Empty),
+				)...),
+			)
+			if m.Error != nil {
+				child.RecordError(m.Error)
+				child.SetStatus(codes.Error, m.Error.Error())
+			}
+			child.End(trace.WithTimestamp(start.Add(m.Duration)))
+		}
+
+		if t.duration == nil {
+			continue
+		}
+		set := metric.WithAttributes(attrs...)
+		t.duration.Record(ctx, m.Duration.Seconds(), set)
+		if m.Error != nil {
+			t.failed.Add(ctx, 1, set)
+		} else {
+			t.applied.Add(ctx, 1, set)
+		}
+	}
+
+	if span == nil {
+		return
+	}
+	span.SetAttributes(
+		attribute.Int("pgdbtemplate.goose.migrations", len(report.Migrations)),
+		attribute.Bool("pgdbtemplate.goose.reused", report.Reused),
+	)
+	if report.Error != nil {
+		span.RecordError(report.Error)
+		span.SetStatus(codes.Error, report.Error.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
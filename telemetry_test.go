@@ -0,0 +1,135 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestTelemetry returns in-memory OpenTelemetry providers.
+func newTestTelemetry() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter, *sdkmetric.MeterProvider, *sdkmetric.ManualReader) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	return tracerProvider, exporter, meterProvider, reader
+}
+
+// collectSums returns values of counters by metric name.
+func collectSums(c *qt.C, reader *sdkmetric.ManualReader) map[string]int64 {
+	var rm metricdata.ResourceMetrics
+	c.Assert(reader.Collect(context.Background(), &rm), qt.IsNil)
+
+	sums := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, point := range data.DataPoints {
+					sums[m.Name] += point.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, point := range data.DataPoints {
+					sums[m.Name] += int64(point.Count)
+				}
+			}
+		}
+	}
+	return sums
+}
+
+func TestMigrationRunnerTelemetry(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	c.Run("Migrations", func(c *qt.C) {
+		c.Parallel()
+
+		tracerProvider, exporter, meterProvider, reader := newTestTelemetry()
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, map[string]string{
+				"00001_create_metrics.sql": `-- +goose Up
+CREATE TABLE goose_otel_metrics (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE goose_otel_metrics;
+`,
+				"00002_broken.sql": `-- +goose Up
+SELECT * FROM goose_otel_missing;
+
+-- +goose Down
+SELECT 1;
+`,
+			}),
+			pgdbtemplategoose.WithTracerProvider(tracerProvider),
+			pgdbtemplategoose.WithMeterProvider(meterProvider),
+		)
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+		defer tm.Cleanup(ctx)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.ErrorMatches, ".*goose_otel_missing.*")
+
+		spans := exporter.GetSpans()
+		c.Assert(spans, qt.HasLen, 3)
+		run := spans[len(spans)-1]
+		c.Assert(run.Name, qt.Equals, "pgdbtemplategoose.RunMigrations")
+		c.Assert(run.Status.Code, qt.Equals, codes.Error)
+
+		for i, version := range []int64{1, 2} {
+			migration := spans[i]
+			c.Assert(migration.Name, qt.Equals, "pgdbtemplategoose.migration")
+			c.Assert(migration.Parent.SpanID(), qt.Equals, run.SpanContext.SpanID())
+			c.Assert(migration.Attributes, qt.Contains, attribute.Int64("pgdbtemplate.goose.version", version))
+			c.Assert(migration.StartTime.Before(run.StartTime), qt.IsFalse)
+			c.Assert(migration.EndTime.After(run.EndTime), qt.IsFalse)
+		}
+		c.Assert(spans[1].StartTime.Before(spans[0].EndTime), qt.IsFalse)
+		c.Assert(spans[0].Status.Code, qt.Equals, codes.Unset)
+		c.Assert(spans[1].Status.Code, qt.Equals, codes.Error)
+
+		c.Assert(collectSums(c, reader), qt.DeepEquals, map[string]int64{
+			"pgdbtemplate.goose.migration.duration": 2,
+			"pgdbtemplate.goose.migrations.applied": 1,
+			"pgdbtemplate.goose.migrations.failed":  1,
+		})
+	})
+
+	c.Run("Failed run", func(c *qt.C) {
+		c.Parallel()
+
+		tracerProvider, exporter, meterProvider, reader := newTestTelemetry()
+		runner := pgdbtemplategoose.NewMigrationRunner(
+			writeMigrations(c, nil),
+			pgdbtemplategoose.WithTracerProvider(tracerProvider),
+			pgdbtemplategoose.WithMeterProvider(meterProvider),
+		)
+		c.Assert(runner.Close(), qt.IsNil)
+
+		err := runner.RunMigrations(ctx, &sqlDBConnection{})
+		c.Assert(err, qt.ErrorIs, pgdbtemplategoose.ErrRunnerClosed)
+
+		spans := exporter.GetSpans()
+		c.Assert(spans, qt.HasLen, 1)
+		c.Assert(spans[0].Name, qt.Equals, "pgdbtemplategoose.RunMigrations")
+		c.Assert(spans[0].Status, qt.Equals, sdktrace.Status{Code: codes.Error, Description: "migration runner is closed"})
+		c.Assert(spans[0].Attributes, qt.Contains, attribute.Int("pgdbtemplate.goose.migrations", 0))
+		c.Assert(collectSums(c, reader), qt.HasLen, 0)
+	})
+}
@@ -0,0 +1,277 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	"github.com/andrei-polukhin/pgdbtemplate-goose/internal/fixture"
+)
+
+// templateLockClassID is the first key of advisory locks taken on the
+// admin database, keeping them apart from locks of other applications.
+// The second key is the hash of the template name.
+const templateLockClassID = 0x676f6f73 // "goos"
+
+// defaultAdminDBName mirrors the pgdbtemplate default.
+const defaultAdminDBName = "postgres"
+
+// TemplateManager is a pgdbtemplate.TemplateManager whose Initialize
+// is serialized across processes building the same template,
+// see NewTemplateManager.
+type TemplateManager struct {
+	*pgdbtemplate.TemplateManager
+
+	provider pgdbtemplate.ConnectionProvider
+	// runners are config.MigrationRunner if it is a *MigrationRunner,
+	// or the stage runners if it is a *Pipeline.
+	runners      []*MigrationRunner
+	templateName string
+	adminDBName  string
+}
+
+// NewTemplateManager creates a template manager which, when config names
+// the template and config.MigrationRunner is a *MigrationRunner created
+// with WithSessionLock, or a *Pipeline with such a stage runner, holds an
+// advisory lock keyed by the template name on the admin database for the
+// whole of Initialize. The lock timeout and retry interval are those of
+// the first such runner.
+//
+// pgdbtemplate checks whether the template exists before creating and
+// migrating it, so without the lock another process could find the
+// template created but not yet migrated. With the lock, processes sharing
+// a template name wait until the first one has finished building it, and
+// then use it as is.
+//
+// Without a template name every manager builds its own uniquely named
+// template, so no lock is needed and none is taken.
+//
+// When the runner, or a stage runner, is created with WithReuse, an existing
+// template is only used if it was built from the same migrations, as told
+// by the fingerprint stored in its version table. A stale template is dropped and rebuilt with
+// ReuseOrRebuild, and Initialize fails with ErrDatabaseDiverged with
+// ReuseOrFail.
+//
+// Example:
+//
+//	runner := NewMigrationRunner(
+//	    migrationsFs,
+//	    WithSessionLock(2*time.Minute, time.Second),
+//	)
+//	tm, err := NewTemplateManager(pgdbtemplate.Config{
+//	    ConnectionProvider: provider,
+//	    MigrationRunner:    runner,
+//	    TemplateName:       "app_template",
+//	})
+func NewTemplateManager(config pgdbtemplate.Config) (*TemplateManager, error) {
+	tm, err := pgdbtemplate.NewTemplateManager(config)
+	if err != nil {
+		return nil, err
+	}
+	var runners []*MigrationRunner
+	switch runner := config.MigrationRunner.(type) {
+	case *MigrationRunner:
+		runners = append(runners, runner)
+	case *Pipeline:
+		for _, stage := range runner.stages {
+			if stage.Runner != nil {
+				runners = append(runners, stage.Runner)
+			}
+		}
+	}
+	adminDBName := config.AdminDBName
+	if adminDBName == "" {
+		adminDBName = defaultAdminDBName
+	}
+	return &TemplateManager{
+		TemplateManager: tm,
+		provider:        config.ConnectionProvider,
+		runners:         runners,
+		templateName:    config.TemplateName,
+		adminDBName:     adminDBName,
+	}, nil
+}
+
+// Initialize creates and migrates the template unless an up-to-date one
+// already exists, holding the template lock if one is configured.
+func (tm *TemplateManager) Initialize(ctx context.Context) (err error) {
+	if tm.templateName == "" || len(tm.runners) == 0 {
+		return tm.TemplateManager.Initialize(ctx)
+	}
+
+	if runner := tm.lockRunner(); runner != nil {
+		unlock, err := tm.lock(ctx, runner)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = errors.Join(err, unlock())
+		}()
+	}
+	if err := tm.checkTemplate(ctx); err != nil {
+		return err
+	}
+	return tm.TemplateManager.Initialize(ctx)
+}
+
+// lockRunner returns the first runner created with WithSessionLock, or nil.
+func (tm *TemplateManager) lockRunner() *MigrationRunner {
+	for _, runner := range tm.runners {
+		if runner.sessionLock != nil {
+			return runner
+		}
+	}
+	return nil
+}
+
+// checkTemplate compares the fingerprints stored in an existing template
+// with the ones of runners created with WithReuse, and drops a stale
+// template so that pgdbtemplate builds it again.
+func (tm *TemplateManager) checkTemplate(ctx context.Context) (err error) {
+	var reusing []*MigrationRunner
+	for _, runner := range tm.runners {
+		if runner.reusePolicy != ReuseDisabled {
+			reusing = append(reusing, runner)
+		}
+	}
+	if len(reusing) == 0 {
+		return nil
+	}
+
+	adminConn, err := tm.provider.Connect(ctx, tm.adminDBName)
+	if err != nil {
+		return fmt.Errorf("failed to connect to admin database: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, adminConn.Close())
+	}()
+
+	var exists bool
+	err = adminConn.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)",
+		tm.templateName,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check if template exists: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	diverged, divergence, err := tm.templateDivergence(ctx, reusing)
+	if err != nil {
+		return err
+	}
+	if diverged == nil {
+		return nil
+	}
+	if diverged.reusePolicy == ReuseOrFail {
+		return fmt.Errorf("%w: template %s: %s", ErrDatabaseDiverged, tm.templateName, divergence)
+	}
+
+	name := fixture.QuoteIdentifier(tm.templateName)
+	if _, err := adminConn.ExecContext(ctx, fmt.Sprintf("ALTER DATABASE %s WITH is_template FALSE", name)); err != nil {
+		return fmt.Errorf("failed to unmark stale template (%s): %w", divergence, err)
+	}
+	if _, err := adminConn.ExecContext(ctx, fmt.Sprintf("DROP DATABASE %s", name)); err != nil {
+		return fmt.Errorf("failed to drop stale template (%s): %w", divergence, err)
+	}
+	return nil
+}
+
+// templateDivergence returns the first of runners whose migrations the
+// existing template was not built from, explaining why, or nil if the
+// template was built from the migrations of all of them.
+func (tm *TemplateManager) templateDivergence(ctx context.Context, runners []*MigrationRunner) (_ *MigrationRunner, _ string, err error) {
+	templateConn, err := tm.provider.Connect(ctx, tm.templateName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to template database: %w", err)
+	}
+	db, release, err := runners[0].extractSQLDB(templateConn)
+	if err != nil {
+		return nil, "", errors.Join(err, templateConn.Close())
+	}
+	// The template cannot be dropped while connected to.
+	defer func() {
+		err = errors.Join(err, release(), templateConn.Close())
+	}()
+
+	for _, runner := range runners {
+		fingerprint, err := runner.Fingerprint()
+		if err != nil {
+			return nil, "", err
+		}
+		for _, table := range runner.versionTables() {
+			stored, err := loadFingerprint(ctx, db, table)
+			if err != nil {
+				return nil, "", err
+			}
+			if stored != fingerprint {
+				return runner, fmt.Sprintf("fingerprint %q of %s does not match %q", stored, table, fingerprint), nil
+			}
+		}
+	}
+	return nil, "", nil
+}
+
+// lock takes the template lock on a dedicated admin database session,
+// with the session lock configuration of runner, and returns the function
+// releasing it and the session.
+func (tm *TemplateManager) lock(ctx context.Context, runner *MigrationRunner) (_ func() error, err error) {
+	config := runner.sessionLock
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	adminConn, err := tm.provider.Connect(ctx, tm.adminDBName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to admin database: %w", err)
+	}
+	db, release, err := runner.extractSQLDB(adminConn)
+	if err != nil {
+		return nil, errors.Join(err, adminConn.Close())
+	}
+	// Advisory locks belong to sessions, so pin one.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to acquire admin connection: %w", err)
+		return nil, errors.Join(err, release(), adminConn.Close())
+	}
+	closeAll := func() error {
+		return errors.Join(conn.Close(), release(), adminConn.Close())
+	}
+
+	deadline := time.Now().Add(config.timeout)
+	for {
+		var locked bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, hashtext($2))",
+			templateLockClassID, tm.templateName).Scan(&locked)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to take template lock: %w", err), closeAll())
+		}
+		if locked {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			return nil, errors.Join(errors.New("failed to take template lock: timed out"), closeAll())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Join(fmt.Errorf("failed to take template lock: %w", ctx.Err()), closeAll())
+		case <-time.After(config.retryInterval):
+		}
+	}
+
+	return func() error {
+		// A fresh context, so that the lock is released even if ctx is done.
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1, hashtext($2))",
+			templateLockClassID, tm.templateName)
+		if err != nil {
+			err = fmt.Errorf("failed to release template lock: %w", err)
+		}
+		return errors.Join(err, closeAll())
+	}, nil
+}
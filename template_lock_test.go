@@ -0,0 +1,128 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestTemplateManager(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	// The migration is slow on purpose, so that the second manager
+	// checks for the template while the first one is still migrating it.
+	migrations := map[string]string{
+		"00001_create_slow.sql": `-- +goose Up
+CREATE TABLE goose_template_lock (id SERIAL PRIMARY KEY);
+SELECT pg_sleep(1);
+
+-- +goose Down
+DROP TABLE goose_template_lock;
+`,
+	}
+
+	for _, test := range []struct {
+		name string
+		// migrationRunner wraps runner into the manager's migration runner.
+		migrationRunner func(runner *pgdbtemplategoose.MigrationRunner) pgdbtemplate.MigrationRunner
+	}{{
+		name: "Concurrent managers build the template once",
+		migrationRunner: func(runner *pgdbtemplategoose.MigrationRunner) pgdbtemplate.MigrationRunner {
+			return runner
+		},
+	}, {
+		name: "Concurrent pipeline managers build the template once",
+		migrationRunner: func(runner *pgdbtemplategoose.MigrationRunner) pgdbtemplate.MigrationRunner {
+			return pgdbtemplategoose.NewPipeline(pgdbtemplategoose.PipelineStage{Name: "app", Runner: runner})
+		},
+	}} {
+		test := test
+		c.Run(test.name, func(c *qt.C) {
+			c.Parallel()
+
+			templateName := fmt.Sprintf("goose_template_lock_%d", time.Now().UnixNano())
+			migrationsFs := writeMigrations(c, migrations)
+
+			runners := make([]*pgdbtemplategoose.MigrationRunner, 2)
+			managers := make([]*pgdbtemplategoose.TemplateManager, 2)
+			for i := range managers {
+				runners[i] = pgdbtemplategoose.NewMigrationRunner(
+					migrationsFs,
+					pgdbtemplategoose.WithSessionLock(time.Minute, 100*time.Millisecond),
+				)
+				tm, err := pgdbtemplategoose.NewTemplateManager(pgdbtemplate.Config{
+					ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+					MigrationRunner:    test.migrationRunner(runners[i]),
+					TemplateName:       templateName,
+				})
+				c.Assert(err, qt.IsNil)
+				managers[i] = tm
+				c.Cleanup(func() { tm.Cleanup(ctx) })
+			}
+
+			var wg sync.WaitGroup
+			errs := make([]error, len(managers))
+			for i, tm := range managers {
+				i, tm := i, tm
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs[i] = tm.Initialize(ctx)
+				}()
+			}
+			wg.Wait()
+			c.Assert(errs, qt.DeepEquals, []error{nil, nil})
+
+			// Only one of the managers ran migrations.
+			var migrated int
+			for _, runner := range runners {
+				if runner.LastReport() != nil {
+					migrated++
+				}
+			}
+			c.Assert(migrated, qt.Equals, 1)
+
+			// The other one sees the complete template.
+			for _, tm := range managers {
+				tm := tm
+				testDB, dbName, err := tm.CreateTestDatabase(ctx)
+				c.Assert(err, qt.IsNil)
+				c.Cleanup(func() {
+					testDB.Close()
+					tm.DropTestDatabase(ctx, dbName)
+				})
+
+				var exists bool
+				err = testDB.QueryRowContext(ctx, "SELECT to_regclass('goose_template_lock') IS NOT NULL").Scan(&exists)
+				c.Assert(err, qt.IsNil)
+				c.Assert(exists, qt.IsTrue)
+			}
+		})
+	}
+
+	c.Run("Invalid timeout", func(c *qt.C) {
+		c.Parallel()
+
+		tm, err := pgdbtemplategoose.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+			MigrationRunner: pgdbtemplategoose.NewMigrationRunner(
+				writeMigrations(c, migrations),
+				pgdbtemplategoose.WithSessionLock(0, time.Second),
+			),
+			TemplateName: fmt.Sprintf("goose_template_lock_%d", time.Now().UnixNano()),
+		})
+		c.Assert(err, qt.IsNil)
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.ErrorMatches, "invalid session lock timeout 0s: must be greater than 0")
+	})
+}
@@ -0,0 +1,108 @@
+package templatetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+)
+
+// Matrix is a set of templates built from the same migrations at different
+// target versions, e.g. to test application code against both the current
+// and the previous schema during rolling deploys. Every template is built
+// lazily, once per process, like a Template.
+type Matrix struct {
+	versions  []int64
+	templates map[int64]*Template
+}
+
+// NewMatrix creates one template per target version, migrated with goose
+// migrations from migrationsFs. Version 0 stands for the latest version.
+// Options configure every underlying pgdbtemplategoose.MigrationRunner;
+// pgdbtemplategoose.WithTargetVersion is set by the matrix.
+//
+// Example:
+//
+//	var matrix = templatetest.NewMatrix(
+//	    pgdbtemplatepq.NewConnectionProvider(connStringFunc),
+//	    os.DirFS("migrations"),
+//	    []int64{41, 0}, // the previous and the latest schema
+//	)
+//
+//	func TestUsers(t *testing.T) {
+//	    for _, version := range matrix.Versions() {
+//	        db := matrix.Database(t, version)
+//	        ...
+//	    }
+//	}
+func NewMatrix(provider pgdbtemplate.ConnectionProvider, migrationsFs fs.FS, versions []int64, options ...pgdbtemplategoose.Option) *Matrix {
+	m := &Matrix{templates: make(map[int64]*Template, len(versions))}
+	for _, version := range versions {
+		if _, ok := m.templates[version]; ok {
+			continue
+		}
+		versionOptions := append(options[:len(options):len(options)], pgdbtemplategoose.WithTargetVersion(version))
+		m.versions = append(m.versions, version)
+		m.templates[version] = New(provider, migrationsFs, versionOptions...)
+	}
+	return m
+}
+
+// Versions returns the target versions of the matrix in the order
+// they were given, without duplicates.
+func (m *Matrix) Versions() []int64 {
+	return append([]int64(nil), m.versions...)
+}
+
+// Template returns the template at the given target version,
+// or nil if the version is not part of the matrix.
+func (m *Matrix) Template(version int64) *Template {
+	return m.templates[version]
+}
+
+// Initialize builds the templates of all versions. Templates are otherwise
+// built on first use, so calling it is only needed to fail early.
+func (m *Matrix) Initialize(ctx context.Context) error {
+	var errs error
+	for _, version := range m.versions {
+		if err := m.templates[version].Initialize(ctx); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("version %d: %w", version, err))
+		}
+	}
+	return errs
+}
+
+// Database returns a fresh database cloned from the template at the given
+// target version, building the template first if needed. The database is
+// closed and dropped when the test and all its subtests complete.
+// Failures, including unknown versions, are reported with tb.Fatal.
+func (m *Matrix) Database(tb testing.TB, version int64) pgdbtemplate.DatabaseConnection {
+	tb.Helper()
+
+	template := m.Template(version)
+	if template == nil {
+		tb.Fatalf("version %d is not part of the template matrix %v", version, m.versions)
+	}
+	return template.Database(tb)
+}
+
+// Close closes the templates of all versions.
+func (m *Matrix) Close(ctx context.Context) error {
+	var errs error
+	for _, version := range m.versions {
+		if err := m.templates[version].Close(ctx); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("version %d: %w", version, err))
+		}
+	}
+	return errs
+}
+
+// Main runs the tests and closes the matrix afterwards. It returns the
+// exit code to pass to os.Exit, as a drop-in for TestMain.
+func (m *Matrix) Main(tm *testing.M) int {
+	return runAndClose(tm, "template matrix", m.Close)
+}
@@ -0,0 +1,67 @@
+package templatetest_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andrei-polukhin/pgdbtemplate-goose/templatetest"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMatrix(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	matrix := templatetest.NewMatrix(
+		pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+		fstest.MapFS{
+			"00001_create_tags.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE templatetest_tags (id SERIAL PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE templatetest_tags;
+`)},
+			"00002_add_label.sql": {Data: []byte(`-- +goose Up
+ALTER TABLE templatetest_tags ADD COLUMN label TEXT;
+
+-- +goose Down
+ALTER TABLE templatetest_tags DROP COLUMN label;
+`)},
+		},
+		[]int64{1, 0, 1},
+	)
+	// Registered first, so that it runs after test databases are dropped.
+	c.Cleanup(func() {
+		c.Check(matrix.Close(ctx), qt.IsNil)
+	})
+
+	c.Assert(matrix.Versions(), qt.DeepEquals, []int64{1, 0})
+	c.Assert(matrix.Template(2), qt.IsNil)
+	c.Assert(matrix.Initialize(ctx), qt.IsNil)
+
+	// Every version has its own template at the requested version.
+	for _, test := range []struct {
+		version    int64
+		migrations int
+		hasLabel   bool
+	}{
+		{version: 1, migrations: 1, hasLabel: false},
+		{version: 0, migrations: 2, hasLabel: true},
+	} {
+		db := matrix.Database(c.TB, test.version).(*pgdbtemplatepq.DatabaseConnection)
+
+		var hasLabel bool
+		err := db.QueryRowContext(ctx, `SELECT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'templatetest_tags' AND column_name = 'label'
+		)`).Scan(&hasLabel)
+		c.Assert(err, qt.IsNil)
+		c.Assert(hasLabel, qt.Equals, test.hasLabel, qt.Commentf("version %d", test.version))
+
+		report := matrix.Template(test.version).Runner().LastReport()
+		c.Assert(report.Migrations, qt.HasLen, test.migrations, qt.Commentf("version %d", test.version))
+	}
+}
@@ -0,0 +1,154 @@
+// Package templatetest wires goose migrations to a pgdbtemplate template
+// manager for tests: the template is built once per process and every test
+// gets a fresh database cloned from it, dropped when the test completes.
+//
+// Example:
+//
+//	var template = templatetest.New(
+//	    pgdbtemplatepq.NewConnectionProvider(connStringFunc),
+//	    os.DirFS("migrations"),
+//	)
+//
+//	func TestMain(m *testing.M) {
+//	    os.Exit(template.Main(m))
+//	}
+//
+//	func TestUsers(t *testing.T) {
+//	    t.Parallel()
+//	    db := template.Database(t)
+//	    ...
+//	}
+package templatetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+)
+
+// Template is a lazily built template database shared by tests.
+type Template struct {
+	provider pgdbtemplate.ConnectionProvider
+	runner   *pgdbtemplategoose.MigrationRunner
+
+	once sync.Once
+	// mu guards tm, so that Close does not race with Initialize.
+	mu  sync.Mutex
+	tm  *pgdbtemplate.TemplateManager
+	err error
+}
+
+// New creates a template migrated with goose migrations from migrationsFs.
+// Options configure the underlying pgdbtemplategoose.MigrationRunner.
+//
+// Nothing is created until the first Initialize or Database call, so New
+// is safe to use in package-level variable declarations.
+func New(provider pgdbtemplate.ConnectionProvider, migrationsFs fs.FS, options ...pgdbtemplategoose.Option) *Template {
+	return &Template{
+		provider: provider,
+		runner:   pgdbtemplategoose.NewMigrationRunner(migrationsFs, options...),
+	}
+}
+
+// Runner returns the migration runner building the template,
+// e.g. to inspect its LastReport.
+func (t *Template) Runner() *pgdbtemplategoose.MigrationRunner {
+	return t.runner
+}
+
+// Initialize creates and migrates the template database once per Template.
+// Later calls return the result of the first one.
+func (t *Template) Initialize(ctx context.Context) error {
+	t.once.Do(func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: t.provider,
+			MigrationRunner:    t.runner,
+		})
+		if err != nil {
+			t.err = fmt.Errorf("failed to create template manager: %w", err)
+			return
+		}
+		if err := tm.Initialize(ctx); err != nil {
+			t.err = fmt.Errorf("failed to initialize template: %w", err)
+			return
+		}
+		t.tm = tm
+	})
+	return t.err
+}
+
+// Database returns a fresh database cloned from the template, building the
+// template first if needed. The database is closed and dropped when the test
+// and all its subtests complete. Failures are reported with tb.Fatal.
+func (t *Template) Database(tb testing.TB) pgdbtemplate.DatabaseConnection {
+	tb.Helper()
+	ctx := context.Background()
+
+	if err := t.Initialize(ctx); err != nil {
+		tb.Fatal(err)
+	}
+
+	conn, dbName, err := t.tm.CreateTestDatabase(ctx)
+	if err != nil {
+		tb.Fatalf("failed to create test database: %v", err)
+	}
+	tb.Cleanup(func() {
+		if err := conn.Close(); err != nil {
+			tb.Errorf("failed to close test database %s: %v", dbName, err)
+		}
+		if err := t.tm.DropTestDatabase(ctx, dbName); err != nil {
+			tb.Errorf("failed to drop test database %s: %v", dbName, err)
+		}
+	})
+	return conn
+}
+
+// Close drops the template database and all test databases still left,
+// and closes the migration runner. The connection provider is left open.
+// Close waits for an Initialize call in progress to finish.
+func (t *Template) Close(ctx context.Context) error {
+	t.mu.Lock()
+	tm := t.tm
+	t.mu.Unlock()
+
+	var errs error
+	if tm != nil {
+		errs = tm.Cleanup(ctx)
+	}
+	return errors.Join(errs, t.runner.Close())
+}
+
+// Main runs the tests and closes the template afterwards. It returns the
+// exit code to pass to os.Exit, as a drop-in for TestMain:
+//
+//	func TestMain(m *testing.M) {
+//	    os.Exit(template.Main(m))
+//	}
+func (t *Template) Main(m *testing.M) int {
+	return runAndClose(m, "template", t.Close)
+}
+
+// runAndClose runs the tests and then calls closeFn, reporting its error
+// on stderr, so that test output on stdout stays intact. A close error
+// turns a passing run into a failing one. It returns the exit code
+// to pass to os.Exit.
+func runAndClose(m *testing.M, name string, closeFn func(context.Context) error) int {
+	code := m.Run()
+	if err := closeFn(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to close %s: %v\n", name, err)
+		if code == 0 {
+			code = 1
+		}
+	}
+	return code
+}
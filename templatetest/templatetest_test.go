@@ -0,0 +1,81 @@
+package templatetest_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	"github.com/andrei-polukhin/pgdbtemplate-goose/templatetest"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+// testConnectionStringFunc creates a connection string for tests.
+func testConnectionStringFunc(dbName string) string {
+	return pgdbtemplate.ReplaceDatabaseInConnectionString(os.Getenv("POSTGRES_CONNECTION_STRING"), dbName)
+}
+
+var template = templatetest.New(
+	pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+	fstest.MapFS{
+		"00001_create_notes.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE templatetest_notes (id SERIAL PRIMARY KEY, body TEXT NOT NULL);
+
+-- +goose Down
+DROP TABLE templatetest_notes;
+`)},
+	},
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("POSTGRES_CONNECTION_STRING") == "" {
+		panic("POSTGRES_CONNECTION_STRING environment variable is required for tests")
+	}
+	os.Exit(template.Main(m))
+}
+
+func TestTemplateDatabase(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	// Tests get isolated databases from the same template.
+	for _, name := range []string{"First", "Second"} {
+		name := name
+		c.Run(name, func(c *qt.C) {
+			c.Parallel()
+
+			db := template.Database(c.TB).(*pgdbtemplatepq.DatabaseConnection)
+			_, err := db.ExecContext(ctx, "INSERT INTO templatetest_notes (body) VALUES ($1)", name)
+			c.Assert(err, qt.IsNil)
+
+			var count int
+			err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM templatetest_notes").Scan(&count)
+			c.Assert(err, qt.IsNil)
+			c.Assert(count, qt.Equals, 1)
+		})
+	}
+
+	// The template was migrated only once.
+	c.Cleanup(func() {
+		c.Assert(template.Runner().LastReport().Migrations, qt.HasLen, 1)
+	})
+}
+
+func TestTemplateInitializeError(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+
+	broken := templatetest.New(
+		pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc),
+		fstest.MapFS{"00001_broken.sql": {Data: []byte("-- +goose Up\nSELECT broken;\n")}},
+	)
+	defer broken.Close(context.Background())
+
+	err := broken.Initialize(context.Background())
+	c.Assert(err, qt.ErrorMatches, "failed to initialize template: .*")
+	// The first result is kept.
+	c.Assert(broken.Initialize(context.Background()), qt.Equals, err)
+}
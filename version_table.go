@@ -0,0 +1,90 @@
+package pgdbtemplategoose
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// versionTable returns the name of goose's version table,
+// qualified with its schema if one was set with WithTableSchema.
+func (r *MigrationRunner) versionTable() string {
+	if r.tableSchema == "" {
+		return r.tableName
+	}
+	return r.tableSchema + "." + r.tableName
+}
+
+// versionTables returns the version tables of a run: one per schema
+// with WithSchemas, the configured one otherwise.
+func (r *MigrationRunner) versionTables() []string {
+	if len(r.schemas) == 0 {
+		return []string{r.versionTable()}
+	}
+	tables := make([]string, len(r.schemas))
+	for i, schema := range r.schemas {
+		tables[i] = schema + "." + r.tableName
+	}
+	return tables
+}
+
+// checkVersionTable validates the version table name and schema.
+func (r *MigrationRunner) checkVersionTable() error {
+	if !schemaNamePattern.MatchString(r.tableName) {
+		return fmt.Errorf("invalid version table name %q: must be a lower-case unquoted identifier", r.tableName)
+	}
+	if r.tableSchema != "" && !schemaNamePattern.MatchString(r.tableSchema) {
+		return fmt.Errorf("invalid version table schema %q: must be a lower-case unquoted identifier", r.tableSchema)
+	}
+	return nil
+}
+
+const (
+	// tableSchemaExistsQuery checks whether the version table schema,
+	// or a schema of WithSchemas, exists.
+	// Existing schemas are not created again, as CREATE SCHEMA IF NOT EXISTS
+	// requires the CREATE privilege on the database even then.
+	tableSchemaExistsQuery = "SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)"
+	// createTableSchemaQuery creates the version table schema,
+	// or a schema of WithSchemas.
+	createTableSchemaQuery = "CREATE SCHEMA IF NOT EXISTS %s"
+)
+
+// ensureTableSchema creates the schema of the version table
+// if one was set with WithTableSchema and it does not exist yet.
+func (r *MigrationRunner) ensureTableSchema(ctx context.Context, db *sql.DB) error {
+	if r.tableSchema == "" {
+		return nil
+	}
+	var exists bool
+	if err := db.QueryRowContext(ctx, tableSchemaExistsQuery, r.tableSchema).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check version table schema %q: %w", r.tableSchema, err)
+	}
+	if exists {
+		return nil
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(createTableSchemaQuery, r.tableSchema)); err != nil {
+		return fmt.Errorf("version table schema %q does not exist and could not be created: %w", r.tableSchema, err)
+	}
+	return nil
+}
+
+// ensureNativeTableSchema is ensureTableSchema for pgx-native mode.
+func (r *MigrationRunner) ensureNativeTableSchema(ctx context.Context, conn *pgx.Conn) error {
+	if r.tableSchema == "" {
+		return nil
+	}
+	var exists bool
+	if err := conn.QueryRow(ctx, tableSchemaExistsQuery, r.tableSchema).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check version table schema %q: %w", r.tableSchema, err)
+	}
+	if exists {
+		return nil
+	}
+	if _, err := conn.Exec(ctx, fmt.Sprintf(createTableSchemaQuery, r.tableSchema)); err != nil {
+		return fmt.Errorf("version table schema %q does not exist and could not be created: %w", r.tableSchema, err)
+	}
+	return nil
+}
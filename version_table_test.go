@@ -0,0 +1,119 @@
+package pgdbtemplategoose_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andrei-polukhin/pgdbtemplate"
+	pgdbtemplategoose "github.com/andrei-polukhin/pgdbtemplate-goose"
+	pgdbtemplatepgx "github.com/andrei-polukhin/pgdbtemplate-pgx"
+	pgdbtemplatepq "github.com/andrei-polukhin/pgdbtemplate-pq"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMigrationRunnerVersionTable(t *testing.T) {
+	t.Parallel()
+	c := qt.New(t)
+	ctx := context.Background()
+
+	migrations := fstest.MapFS{
+		"00001_create_events.sql": {Data: []byte("-- +goose Up\nCREATE TABLE goose_version_table_events (id SERIAL PRIMARY KEY);\n")},
+		"00002_add_name.sql":      {Data: []byte("-- +goose Up\nALTER TABLE goose_version_table_events ADD COLUMN name TEXT;\n")},
+	}
+
+	// otherTables lists tables other than the migrated one in the template
+	// built with the provider and the runner.
+	otherTables := func(c *qt.C, provider pgdbtemplate.ConnectionProvider, runner pgdbtemplate.MigrationRunner) string {
+		tm, err := pgdbtemplate.NewTemplateManager(pgdbtemplate.Config{
+			ConnectionProvider: provider,
+			MigrationRunner:    runner,
+		})
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() { tm.Cleanup(ctx) })
+
+		err = tm.Initialize(ctx)
+		c.Assert(err, qt.IsNil)
+
+		testDB, dbName, err := tm.CreateTestDatabase(ctx)
+		c.Assert(err, qt.IsNil)
+		c.Cleanup(func() {
+			testDB.Close()
+			tm.DropTestDatabase(ctx, dbName)
+		})
+
+		var tables string
+		err = testDB.QueryRowContext(ctx, `SELECT string_agg(schemaname || '.' || tablename, ',' ORDER BY schemaname, tablename)
+			FROM pg_tables
+			WHERE schemaname NOT IN ('pg_catalog', 'information_schema')
+			AND tablename <> 'goose_version_table_events'`).Scan(&tables)
+		c.Assert(err, qt.IsNil)
+		return tables
+	}
+
+	for _, test := range []struct {
+		about    string
+		provider func(c *qt.C) pgdbtemplate.ConnectionProvider
+		options  []pgdbtemplategoose.Option
+	}{{
+		about: "database/sql",
+		provider: func(c *qt.C) pgdbtemplate.ConnectionProvider {
+			return pgdbtemplatepq.NewConnectionProvider(testConnectionStringFunc)
+		},
+	}, {
+		about: "pgx-native",
+		provider: func(c *qt.C) pgdbtemplate.ConnectionProvider {
+			provider := pgdbtemplatepgx.NewConnectionProvider(testConnectionStringFunc)
+			c.Cleanup(provider.Close)
+			return provider
+		},
+		options: []pgdbtemplategoose.Option{pgdbtemplategoose.WithPgxNative()},
+	}} {
+		test := test
+		c.Run(test.about, func(c *qt.C) {
+			c.Parallel()
+
+			runner := pgdbtemplategoose.NewMigrationRunner(migrations, append([]pgdbtemplategoose.Option{
+				pgdbtemplategoose.WithTableSchema("goose_ops"),
+				pgdbtemplategoose.WithTableName("goose_schema_migrations"),
+			}, test.options...)...)
+			// The version table is the only other table.
+			c.Assert(otherTables(c, test.provider(c), runner), qt.Equals, "goose_ops.goose_schema_migrations")
+			c.Assert(runner.LastReport().Migrations, qt.HasLen, 2)
+		})
+	}
+
+	c.Run("Invalid configuration", func(c *qt.C) {
+		c.Parallel()
+
+		for _, test := range []struct {
+			about   string
+			options []pgdbtemplategoose.Option
+			err     string
+		}{{
+			about:   "quoted table name",
+			options: []pgdbtemplategoose.Option{pgdbtemplategoose.WithTableName("Schema Migrations")},
+			err:     `invalid version table name "Schema Migrations": must be a lower-case unquoted identifier`,
+		}, {
+			about:   "qualified table name",
+			options: []pgdbtemplategoose.Option{pgdbtemplategoose.WithTableName("ops.schema_migrations")},
+			err:     `invalid version table name "ops.schema_migrations": must be a lower-case unquoted identifier`,
+		}, {
+			about:   "quoted schema",
+			options: []pgdbtemplategoose.Option{pgdbtemplategoose.WithTableSchema("Ops")},
+			err:     `invalid version table schema "Ops": must be a lower-case unquoted identifier`,
+		}, {
+			about: "per-schema runs",
+			options: []pgdbtemplategoose.Option{
+				pgdbtemplategoose.WithTableSchema("ops"),
+				pgdbtemplategoose.WithSchemas("tenant_a"),
+			},
+			err: "version table schema is not supported with per-schema runs: every schema has its own version table",
+		}} {
+			// The check fails before the connection is used.
+			runner := pgdbtemplategoose.NewMigrationRunner(migrations, test.options...)
+			err := runner.RunMigrations(ctx, &sqlDBConnection{})
+			c.Assert(err, qt.ErrorMatches, test.err, qt.Commentf(test.about))
+		}
+	})
+}